@@ -0,0 +1,170 @@
+package main
+
+import (
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	chatpb "api-gateway/chat-service/script"
+
+	"github.com/gin-gonic/gin"
+)
+
+const attachmentChunkSize = 32 * 1024
+
+func attachmentsJSON(attachments []*chatpb.Attachment) []gin.H {
+	result := []gin.H{}
+	for _, a := range attachments {
+		result = append(result, attachmentJSON(a))
+	}
+	return result
+}
+
+func attachmentJSON(a *chatpb.Attachment) gin.H {
+	return gin.H{
+		"id":            a.Id,
+		"name":          a.Name,
+		"mime_type":     a.MimeType,
+		"size":          a.Size,
+		"checksum":      a.Checksum,
+		"url":           a.Url,
+		"width":         a.Width,
+		"height":        a.Height,
+		"thumbnail_url": a.ThumbnailUrl,
+		"status":        a.Status,
+	}
+}
+
+// uploadAttachmentHandler menerima multipart form dengan field "file" dan
+// meneruskannya ke chat-service sedikit demi sedikit
+func uploadAttachmentHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file"})
+		return
+	}
+	defer file.Close()
+
+	// MIME type ditentukan dari isi file, bukan dari header yang dikirim client
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	mimeType := http.DetectContentType(head[:n])
+	if mimeType == "application/octet-stream" && fileHeader.Header.Get("Content-Type") != "" {
+		mimeType = fileHeader.Header.Get("Content-Type")
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file"})
+		return
+	}
+
+	stream, err := grpcClient.UploadAttachment(ctx)
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload attachment"})
+		return
+	}
+
+	err = stream.Send(&chatpb.UploadAttachmentRequest{Data: &chatpb.UploadAttachmentRequest_Info{Info: &chatpb.AttachmentInfo{
+		Name:     fileHeader.Filename,
+		MimeType: mimeType,
+		Size:     fileHeader.Size,
+	}}})
+
+	buf := make([]byte, attachmentChunkSize)
+	for err == nil {
+		var n int
+		n, err = file.Read(buf)
+		if n > 0 {
+			if sendErr := stream.Send(&chatpb.UploadAttachmentRequest{Data: &chatpb.UploadAttachmentRequest_Chunk{Chunk: buf[:n]}}); sendErr != nil {
+				err = sendErr
+			}
+		}
+	}
+	if err != io.EOF {
+		log.Printf("Error streaming attachment: %v", err)
+	}
+
+	// Jika chat-service menolak upload, alasannya dikembalikan oleh CloseAndRecv
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to upload attachment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attachmentJSON(res))
+}
+
+func downloadAttachmentHandler(c *gin.Context) {
+	streamAttachment(c, false)
+}
+
+func downloadThumbnailHandler(c *gin.Context) {
+	streamAttachment(c, true)
+}
+
+// streamAttachment hanya bisa dipakai peserta percakapan lampiran,
+// pengecekan dilakukan oleh chat-service berdasarkan token
+func streamAttachment(c *gin.Context, thumbnail bool) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	attachmentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+
+	stream, err := grpcClient.DownloadAttachment(ctx, &chatpb.DownloadAttachmentRequest{
+		AttachmentId: int32(attachmentID),
+		Thumbnail:    thumbnail,
+	})
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to download attachment"})
+		return
+	}
+
+	first, err := stream.Recv()
+	if err != nil || first.GetInfo() == nil {
+		log.Print(err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+	info := first.GetInfo()
+
+	c.Header("Content-Type", info.MimeType)
+	c.Header("Content-Length", strconv.FormatInt(info.Size, 10))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Name}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			log.Printf("Error downloading attachment %d: %v", attachmentID, err)
+			return
+		}
+
+		if _, err := c.Writer.Write(res.GetChunk()); err != nil {
+			return
+		}
+	}
+}
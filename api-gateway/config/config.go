@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
@@ -13,7 +15,12 @@ type Config struct {
 	Seeder    bool
 	PublicKey string
 	Database  Database
-	Redis     Redis
+
+	// MetricsAddr adalah alamat HTTP internal untuk /debug/vars (expvar),
+	// kosong berarti nonaktif. Jangan diekspos ke publik.
+	MetricsAddr string
+
+	WebSocket WebSocket
 }
 
 type Database struct {
@@ -30,10 +37,13 @@ type Database struct {
 	DBMaxLifeTime  int
 }
 
-type Redis struct {
-	Url      string
-	Password string
-	Prefix   string
+// WebSocket mengatur heartbeat dan antrean kirim tiap koneksi WebSocket
+type WebSocket struct {
+	PingInterval   time.Duration
+	PongTimeout    time.Duration
+	WriteTimeout   time.Duration
+	SendQueueSize  int
+	MaxMessageSize int64
 }
 
 func SetConfig() (Config, error) {
@@ -51,6 +61,12 @@ func SetConfig() (Config, error) {
 	viper.SetDefault("DBUser", "postgres")
 	viper.SetDefault("DBPassword", "admin")
 	viper.SetDefault("DBName", "database")
+	viper.SetDefault("WS_PING_INTERVAL_SECONDS", 25)
+	viper.SetDefault("WS_PONG_TIMEOUT_SECONDS", 60)
+	viper.SetDefault("WS_WRITE_TIMEOUT_SECONDS", 10)
+	viper.SetDefault("WS_SEND_QUEUE_SIZE", 64)
+	viper.SetDefault("WS_MAX_MESSAGE_BYTES", 65536)
+	viper.SetDefault("METRICS_ADDR", "127.0.0.1:50065")
 
 	viper.AutomaticEnv()
 
@@ -67,6 +83,8 @@ func SetConfig() (Config, error) {
 		Seeder:    viper.GetBool("SEEDER"),
 		PublicKey: viper.GetString("PUBLIC_KEY"),
 
+		MetricsAddr: viper.GetString("METRICS_ADDR"),
+
 		Database: Database{
 			DBName:         viper.GetString("DB_NAME"),
 			DBHost:         viper.GetString("DB_HOST"),
@@ -81,10 +99,12 @@ func SetConfig() (Config, error) {
 			DBMaxLifeTime:  viper.GetInt("DB_MAX_LIFE_TIME"),
 		},
 
-		Redis: Redis{
-			Url:      viper.GetString("REDIS_URL"),
-			Password: viper.GetString("REDIS_PASSWORD"),
-			Prefix:   viper.GetString("REDIS_PREFIX"),
+		WebSocket: WebSocket{
+			PingInterval:   time.Duration(viper.GetInt("WS_PING_INTERVAL_SECONDS")) * time.Second,
+			PongTimeout:    time.Duration(viper.GetInt("WS_PONG_TIMEOUT_SECONDS")) * time.Second,
+			WriteTimeout:   time.Duration(viper.GetInt("WS_WRITE_TIMEOUT_SECONDS")) * time.Second,
+			SendQueueSize:  viper.GetInt("WS_SEND_QUEUE_SIZE"),
+			MaxMessageSize: viper.GetInt64("WS_MAX_MESSAGE_BYTES"),
 		},
 	}
 
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	chatpb "api-gateway/chat-service/script"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func listConversationsHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	res, err := grpcClient.ListConversations(ctx, &emptypb.Empty{})
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list conversations"})
+		return
+	}
+
+	conversations := []gin.H{}
+	for _, conv := range res.Conversations {
+		participants := []gin.H{}
+		for _, p := range conv.Participants {
+			participants = append(participants, gin.H{
+				"user_id":    p.UserId,
+				"first_name": p.FirstName,
+				"last_name":  p.LastName,
+			})
+		}
+
+		var lastMessage gin.H
+		if conv.LastMessage != nil {
+			lastMessage = gin.H{
+				"id":         conv.LastMessage.Id,
+				"sender_id":  conv.LastMessage.SenderId,
+				"content":    conv.LastMessage.Content,
+				"created_at": conv.LastMessage.CreatedAt,
+			}
+		}
+
+		conversations = append(conversations, gin.H{
+			"id":                conv.Id,
+			"type":              conv.Type,
+			"group_id":          conv.GroupId,
+			"name":              conv.Name,
+			"participants":      participants,
+			"last_message":      lastMessage,
+			"last_activity_at":  conv.LastActivityAt,
+			"unread_count":      conv.UnreadCount,
+			"retention_seconds": conv.RetentionSeconds,
+		})
+	}
+
+	c.JSON(http.StatusOK, conversations)
+}
+
+func markReadHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	conversationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	var req chatpb.MarkReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	req.ConversationId = int32(conversationID)
+
+	res, err := grpcClient.MarkRead(ctx, &req)
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark messages as read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": res.Status, "updated": res.Updated})
+}
+
+// setRetentionHandler: PUT /chat/conversations/:id/retention {"retention_seconds": 86400}
+// retention_seconds 0 menonaktifkan pesan sementara
+func setRetentionHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	conversationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	var req chatpb.SetConversationRetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	req.ConversationId = int32(conversationID)
+
+	res, err := grpcClient.SetConversationRetention(ctx, &req)
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update conversation retention"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id":   res.ConversationId,
+		"retention_seconds": res.RetentionSeconds,
+	})
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	chatpb "api-gateway/chat-service/script"
+
+	"github.com/gin-gonic/gin"
+)
+
+// forwardMessageHandler: POST /chat/messages/:id/forward
+// {"conversation_ids": [3, 7]}
+func forwardMessageHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	messageID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var body struct {
+		ConversationIDs []int32 `json:"conversation_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	res, err := grpcClient.ForwardMessage(ctx, &chatpb.ForwardMessageRequest{
+		MessageId:       int32(messageID),
+		ConversationIds: body.ConversationIDs,
+	})
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to forward message"})
+		return
+	}
+
+	messages := []gin.H{}
+	for _, msg := range res.Messages {
+		messages = append(messages, messageJSON(msg))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": messages})
+}
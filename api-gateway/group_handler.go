@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	chatpb "api-gateway/chat-service/script"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// tokenContext meneruskan header token ke metadata gRPC
+func tokenContext(c *gin.Context) (context.Context, bool) {
+	token := c.GetHeader("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	md := metadata.Pairs("token", token)
+	return metadata.NewOutgoingContext(context.Background(), md), true
+}
+
+func groupJSON(group *chatpb.Group) gin.H {
+	members := []gin.H{}
+	for _, member := range group.Members {
+		members = append(members, gin.H{
+			"user_id":    member.UserId,
+			"first_name": member.FirstName,
+			"role":       member.Role,
+		})
+	}
+
+	return gin.H{
+		"id":         group.Id,
+		"name":       group.Name,
+		"owner_id":   group.OwnerId,
+		"members":    members,
+		"created_at": group.CreatedAt,
+	}
+}
+
+func createGroupHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	var req chatpb.CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	res, err := grpcClient.CreateGroup(ctx, &req)
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, groupJSON(res))
+}
+
+func listGroupsHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	res, err := grpcClient.ListGroups(ctx, &emptypb.Empty{})
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list groups"})
+		return
+	}
+
+	groups := []gin.H{}
+	for _, group := range res.Groups {
+		groups = append(groups, groupJSON(group))
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+func addGroupMembersHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	var req chatpb.AddMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	req.GroupId = int32(groupID)
+
+	res, err := grpcClient.AddMembers(ctx, &req)
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add group members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, groupJSON(res))
+}
+
+func removeGroupMemberHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	res, err := grpcClient.RemoveMember(ctx, &chatpb.RemoveMemberRequest{
+		GroupId: int32(groupID),
+		UserId:  int32(userID),
+	})
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove group member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": res.Status})
+}
+
+func leaveGroupHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	res, err := grpcClient.LeaveGroup(ctx, &chatpb.LeaveGroupRequest{GroupId: int32(groupID)})
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": res.Status})
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	chatpb "api-gateway/chat-service/script"
+
+	"github.com/gin-gonic/gin"
+)
+
+// historyRequest membaca query ?before=, ?after= dan ?limit=
+func historyRequest(c *gin.Context) (*chatpb.ListMessageHistoryRequest, bool) {
+	req := &chatpb.ListMessageHistoryRequest{}
+
+	if before := c.Query("before"); before != "" {
+		req.Cursor = before
+		req.Direction = "before"
+	} else if after := c.Query("after"); after != "" {
+		req.Cursor = after
+		req.Direction = "after"
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		limitInt, err := strconv.Atoi(limit)
+		if err != nil || limitInt < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return nil, false
+		}
+		req.Limit = int32(limitInt)
+	}
+
+	return req, true
+}
+
+func messageJSON(msg *chatpb.ChatMessage) gin.H {
+	var replyTo gin.H
+	if msg.ReplyTo != nil {
+		replyTo = gin.H{
+			"id":        msg.ReplyTo.Id,
+			"sender_id": msg.ReplyTo.SenderId,
+			"content":   msg.ReplyTo.Content,
+			"deleted":   msg.ReplyTo.Deleted,
+		}
+	}
+
+	return gin.H{
+		"id":                  msg.Id,
+		"conversation_id":     msg.ConversationId,
+		"sender_id":           msg.SenderId,
+		"receiver_id":         msg.ReceiverId,
+		"group_id":            msg.GroupId,
+		"content":             msg.Content,
+		"content_type":        msg.ContentType,
+		"created_at":          msg.CreatedAt,
+		"edited_at":           msg.EditedAt,
+		"reply_to_message_id": msg.ReplyToMessageId,
+		"thread_root_id":      msg.ThreadRootId,
+		"reply_count":         msg.ReplyCount,
+		"reply_to":            replyTo,
+		"reactions":           reactionsJSON(msg.Reactions),
+		"attachments":         attachmentsJSON(msg.Attachments),
+		"client_message_id":   msg.ClientMessageId,
+		"expires_at":          msg.ExpiresAt,
+
+		"forwarded_from_message_id": msg.ForwardedFromMessageId,
+		"forwarded_from_sender_id":  msg.ForwardedFromSenderId,
+	}
+}
+
+func historyJSON(res *chatpb.ListMessageHistoryResponse) gin.H {
+	messages := []gin.H{}
+	for _, msg := range res.Messages {
+		messages = append(messages, messageJSON(msg))
+	}
+
+	return gin.H{
+		"messages":    messages,
+		"next_cursor": res.NextCursor,
+		"has_more":    res.HasMore,
+	}
+}
+
+func listMessageHistoryHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	peerID, err := strconv.Atoi(c.Param("peer_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid peer ID"})
+		return
+	}
+
+	req, ok := historyRequest(c)
+	if !ok {
+		return
+	}
+	req.PeerId = int32(peerID)
+
+	res, err := grpcClient.ListMessageHistory(ctx, req)
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, historyJSON(res))
+}
+
+func listGroupMessageHistoryHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	req, ok := historyRequest(c)
+	if !ok {
+		return
+	}
+	req.GroupId = int32(groupID)
+
+	res, err := grpcClient.ListMessageHistory(ctx, req)
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, historyJSON(res))
+}
+
+func listThreadHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	messageID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	req := &chatpb.ListThreadRequest{MessageId: int32(messageID), Cursor: c.Query("after")}
+	if limit := c.Query("limit"); limit != "" {
+		limitInt, err := strconv.Atoi(limit)
+		if err != nil || limitInt < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		req.Limit = int32(limitInt)
+	}
+
+	res, err := grpcClient.ListThread(ctx, req)
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list thread"})
+		return
+	}
+
+	replies := []gin.H{}
+	for _, msg := range res.Replies {
+		replies = append(replies, messageJSON(msg))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"root":        messageJSON(res.Root),
+		"replies":     replies,
+		"next_cursor": res.NextCursor,
+		"has_more":    res.HasMore,
+	})
+}
@@ -8,6 +8,7 @@ import (
 
 	authpb "api-gateway/auth-service"
 	chatpb "api-gateway/chat-service/script"
+	"api-gateway/config"
 	"api-gateway/helper"
 	"api-gateway/middleware"
 	userpb "api-gateway/user-service/proto"
@@ -26,6 +27,11 @@ var (
 )
 
 func main() {
+	cfg, err := config.SetConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
 	if err := helper.InitDB(); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
@@ -70,14 +76,53 @@ func main() {
 	router.Use(authMiddleware.Authentication())
 
 	// Routing untuk User Service
-	router.GET("/ws", websocket.WsHandler(grpcClient))
+	router.GET("/ws", websocket.WsHandler(grpcClient, cfg.WebSocket))
 	router.GET("/users", getAllUsersHandler)
 	router.PUT("/users/:id", updateUserHandler)
 
 	// Routing untuk Chat Service
 	router.POST("/chat/send", sendMessageHandler)
 	router.GET("/chat/messages", listMessageHandler)
-	router.GET("/chat/messages/:sender_id", listMessagesBySenderHandler)
+	router.GET("/chat/messages/:peer_id", listMessageHistoryHandler)
+	router.PUT("/chat/messages/:id", editMessageHandler)
+	router.DELETE("/chat/messages/:id", deleteMessageHandler)
+	router.POST("/chat/messages/:id/reactions", addReactionHandler)
+	router.DELETE("/chat/messages/:id/reactions/:emoji", removeReactionHandler)
+	router.POST("/chat/messages/:id/pin", pinMessageHandler)
+	router.DELETE("/chat/messages/:id/pin", unpinMessageHandler)
+	router.POST("/chat/messages/:id/forward", forwardMessageHandler)
+	router.GET("/chat/threads/:id", listThreadHandler)
+	router.POST("/chat/attachments", uploadAttachmentHandler)
+	router.GET("/chat/attachments/:id", downloadAttachmentHandler)
+	router.GET("/chat/attachments/:id/thumbnail", downloadThumbnailHandler)
+	router.GET("/chat/conversations", listConversationsHandler)
+	router.POST("/chat/conversations/:id/read", markReadHandler)
+	router.PUT("/chat/conversations/:id/retention", setRetentionHandler)
+	router.GET("/chat/conversations/:id/pins", listPinnedHandler)
+	router.GET("/chat/sync", syncHandler)
+	router.GET("/chat/search", searchHandler)
+	router.GET("/chat/mentions", listMentionsHandler)
+	router.POST("/chat/scheduled", scheduleMessageHandler)
+	router.GET("/chat/scheduled", listScheduledMessagesHandler)
+	router.DELETE("/chat/scheduled/:id", cancelScheduledMessageHandler)
+
+	// Routing untuk Group Chat
+	router.POST("/chat/groups", createGroupHandler)
+	router.GET("/chat/groups", listGroupsHandler)
+	router.POST("/chat/groups/:id/members", addGroupMembersHandler)
+	router.DELETE("/chat/groups/:id/members/:user_id", removeGroupMemberHandler)
+	router.POST("/chat/groups/:id/leave", leaveGroupHandler)
+	router.GET("/chat/groups/:id/messages", listGroupMessageHistoryHandler)
+
+	// Statistik expvar hanya dilayani di listener internal, bukan di router publik
+	if cfg.MetricsAddr != "" {
+		go func() {
+			log.Printf("Metrics server started on %s", cfg.MetricsAddr)
+			if err := http.ListenAndServe(cfg.MetricsAddr, nil); err != nil {
+				log.Printf("Metrics server stopped: %v", err)
+			}
+		}()
+	}
 
 	log.Println("API Gateway running on port 50051...")
 	router.Run(":50051")
@@ -218,6 +263,38 @@ func sendMessageHandler(c *gin.Context) {
 	if content, ok := rawMsg["content"].(string); ok {
 		req.Content = content
 	}
+	if replyTo, ok := rawMsg["reply_to_message_id"].(float64); ok {
+		req.ReplyToMessageId = int32(replyTo)
+	}
+	if inThread, ok := rawMsg["in_thread"].(bool); ok {
+		req.InThread = inThread
+	}
+	if clientMessageID, ok := rawMsg["client_message_id"].(string); ok {
+		req.ClientMessageId = clientMessageID
+	}
+	if attachmentIDs, ok := rawMsg["attachment_ids"].([]interface{}); ok {
+		for _, id := range attachmentIDs {
+			if idFloat, ok := id.(float64); ok {
+				req.AttachmentIds = append(req.AttachmentIds, int32(idFloat))
+			}
+		}
+	}
+
+	md := metadata.Pairs("token", token)
+	ctx := metadata.NewOutgoingContext(context.Background(), md)
+
+	if groupID, ok := rawMsg["group_id"].(float64); ok && groupID > 0 {
+		req.GroupId = int32(groupID)
+		res, err := grpcClient.SendMessage(ctx, &req)
+		if err != nil {
+			log.Printf("Error sending message to group %d: %v", int(groupID), err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to send message to group"})
+			return
+		}
+
+		c.JSON(http.StatusOK, sendResponseJSON(res))
+		return
+	}
 
 	receiverIDRaw := rawMsg["receiver_id"]
 
@@ -242,50 +319,31 @@ func sendMessageHandler(c *gin.Context) {
 		return
 	}
 
-	for _, receiverID := range req.ReceiverId {
-		log.Printf("Forwarding message to receiver ID %d", receiverID)
-		if err := helper.ForwardToGrpc(req.Content, []int{int(receiverID)}, token); err != nil {
-			log.Printf("Message content length: %d", len(req.Content))
-			log.Printf("Receiver IDs: %v", req.ReceiverId)
-			log.Printf("Error forwarding message to receiver %d: %v", receiverID, err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to forward message to receiver"})
-			// conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("Failed to forward message to receiver %d", receiverID)))
-			return
-		}
-	}
-
-	c.JSON(http.StatusOK, gin.H{"status": "successfully send message"})
-}
-
-func listMessageHandler(c *gin.Context) {
-	token := c.GetHeader("token")
-	if token == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Unauthorized"})
-		return
-	}
-
-	md := metadata.Pairs("token", token)
-	ctx := metadata.NewOutgoingContext(context.Background(), md)
-
-	res, err := grpcClient.ListMessage(ctx, &emptypb.Empty{})
+	res, err := grpcClient.SendMessage(ctx, &req)
 	if err != nil {
-		log.Print(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list messages"})
+		log.Printf("Error sending message to receivers %v: %v", req.ReceiverId, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to send message to receiver"})
 		return
 	}
 
+	c.JSON(http.StatusOK, sendResponseJSON(res))
+}
+
+// sendResponseJSON tetap menyertakan status lama agar client lama tidak berubah
+func sendResponseJSON(res *chatpb.SendMessageResponse) gin.H {
 	messages := []gin.H{}
 	for _, msg := range res.Messages {
-		messages = append(messages, gin.H{
-			"sender":  msg.Sender,
-			"message": msg.Message,
-		})
+		messages = append(messages, messageJSON(msg))
 	}
 
-	c.JSON(http.StatusOK, messages)
+	return gin.H{
+		"status":    "successfully send message",
+		"messages":  messages,
+		"duplicate": res.Duplicate,
+	}
 }
 
-func listMessagesBySenderHandler(c *gin.Context) {
+func listMessageHandler(c *gin.Context) {
 	token := c.GetHeader("token")
 	if token == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Unauthorized"})
@@ -295,36 +353,20 @@ func listMessagesBySenderHandler(c *gin.Context) {
 	md := metadata.Pairs("token", token)
 	ctx := metadata.NewOutgoingContext(context.Background(), md)
 
-	senderID := c.Param("sender_id")
-
-	senderIDInt, err := strconv.Atoi(senderID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sender ID"})
-		return
-	}
-
-	// Konversi ke int32
-	senderIDInt32 := int32(senderIDInt)
-
-	// Panggil service dengan senderID bertipe int32
-	res, err := grpcClient.ListMessageBySender(ctx, &chatpb.ListMessageBySenderRequest{
-		SenderId: senderIDInt32,
-	})
+	res, err := grpcClient.ListMessage(ctx, &emptypb.Empty{})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list messages by sender"})
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list messages"})
 		return
 	}
 
-	// Siapkan respon
 	messages := []gin.H{}
 	for _, msg := range res.Messages {
 		messages = append(messages, gin.H{
+			"sender":  msg.Sender,
 			"message": msg.Message,
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"sender_name": res.SenderName,
-		"messages":    messages,
-	})
+	c.JSON(http.StatusOK, messages)
 }
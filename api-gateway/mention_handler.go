@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	chatpb "api-gateway/chat-service/script"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listMentionsHandler: GET /chat/mentions?cursor=&limit=
+func listMentionsHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	req := &chatpb.ListMentionsRequest{Cursor: c.Query("cursor")}
+	if limit := c.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		req.Limit = int32(value)
+	}
+
+	res, err := grpcClient.ListMentions(ctx, req)
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list mentions"})
+		return
+	}
+
+	messages := []gin.H{}
+	for _, msg := range res.Messages {
+		messages = append(messages, messageJSON(msg))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages":    messages,
+		"next_cursor": res.NextCursor,
+		"has_more":    res.HasMore,
+	})
+}
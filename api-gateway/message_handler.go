@@ -0,0 +1,128 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	chatpb "api-gateway/chat-service/script"
+
+	"github.com/gin-gonic/gin"
+)
+
+func editMessageHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	messageID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var req chatpb.EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	req.MessageId = int32(messageID)
+
+	res, err := grpcClient.EditMessage(ctx, &req)
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, messageJSON(res))
+}
+
+func deleteMessageHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	messageID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	res, err := grpcClient.DeleteMessage(ctx, &chatpb.DeleteMessageRequest{MessageId: int32(messageID)})
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": res.Status})
+}
+
+func addReactionHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	messageID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var req chatpb.ReactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	req.MessageId = int32(messageID)
+
+	res, err := grpcClient.AddReaction(ctx, &req)
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add reaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": res.Status, "reactions": reactionsJSON(res.Reactions)})
+}
+
+func removeReactionHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	messageID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	res, err := grpcClient.RemoveReaction(ctx, &chatpb.ReactionRequest{
+		MessageId: int32(messageID),
+		Emoji:     c.Param("emoji"),
+	})
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove reaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": res.Status, "reactions": reactionsJSON(res.Reactions)})
+}
+
+func reactionsJSON(reactions []*chatpb.ReactionCount) []gin.H {
+	result := []gin.H{}
+	for _, r := range reactions {
+		result = append(result, gin.H{
+			"emoji":   r.Emoji,
+			"count":   r.Count,
+			"reacted": r.Reacted,
+		})
+	}
+	return result
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	chatpb "api-gateway/chat-service/script"
+
+	"github.com/gin-gonic/gin"
+)
+
+func pinsJSON(pins []*chatpb.PinnedMessage) []gin.H {
+	result := []gin.H{}
+	for _, pin := range pins {
+		result = append(result, gin.H{
+			"message":   messageJSON(pin.Message),
+			"pinned_by": pin.PinnedBy,
+			"pinned_at": pin.PinnedAt,
+		})
+	}
+	return result
+}
+
+func pinMessageHandler(c *gin.Context) {
+	updatePin(c, true)
+}
+
+func unpinMessageHandler(c *gin.Context) {
+	updatePin(c, false)
+}
+
+func updatePin(c *gin.Context, pinned bool) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	messageID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	req := &chatpb.PinRequest{MessageId: int32(messageID)}
+
+	var res *chatpb.PinResponse
+	if pinned {
+		res, err = grpcClient.PinMessage(ctx, req)
+	} else {
+		res, err = grpcClient.UnpinMessage(ctx, req)
+	}
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update pin"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": res.Status, "pins": pinsJSON(res.Pins)})
+}
+
+func listPinnedHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	conversationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	res, err := grpcClient.ListPinned(ctx, &chatpb.ListPinnedRequest{ConversationId: int32(conversationID)})
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pinned messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pinsJSON(res.Pins))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	chatpb "api-gateway/chat-service/script"
+
+	"github.com/gin-gonic/gin"
+)
+
+func scheduledMessageJSON(scheduled *chatpb.ScheduledMessage) gin.H {
+	message := gin.H{}
+	if msg := scheduled.Message; msg != nil {
+		message = gin.H{
+			"receiver_id":         msg.ReceiverId,
+			"group_id":            msg.GroupId,
+			"content":             msg.Content,
+			"reply_to_message_id": msg.ReplyToMessageId,
+			"in_thread":           msg.InThread,
+			"attachment_ids":      msg.AttachmentIds,
+		}
+	}
+
+	return gin.H{
+		"id":          scheduled.Id,
+		"message":     message,
+		"send_at":     scheduled.SendAt,
+		"status":      scheduled.Status,
+		"error":       scheduled.Error,
+		"created_at":  scheduled.CreatedAt,
+		"sent_at":     scheduled.SentAt,
+		"message_ids": scheduled.MessageIds,
+	}
+}
+
+// scheduleMessageHandler: POST /chat/scheduled
+// {"send_at": "2024-05-01T09:00:00+07:00", "message": {"receiver_id": [2], "content": "..."}}
+func scheduleMessageHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	var req chatpb.ScheduleMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	res, err := grpcClient.ScheduleMessage(ctx, &req)
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to schedule message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduledMessageJSON(res))
+}
+
+// listScheduledMessagesHandler: GET /chat/scheduled?status=pending
+func listScheduledMessagesHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	res, err := grpcClient.ListScheduledMessages(ctx, &chatpb.ListScheduledMessagesRequest{Status: c.Query("status")})
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list scheduled messages"})
+		return
+	}
+
+	scheduled := []gin.H{}
+	for _, s := range res.ScheduledMessages {
+		scheduled = append(scheduled, scheduledMessageJSON(s))
+	}
+
+	c.JSON(http.StatusOK, scheduled)
+}
+
+func cancelScheduledMessageHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled message ID"})
+		return
+	}
+
+	res, err := grpcClient.CancelScheduledMessage(ctx, &chatpb.CancelScheduledMessageRequest{Id: int32(id)})
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to cancel scheduled message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduledMessageJSON(res))
+}
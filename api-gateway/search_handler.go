@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	chatpb "api-gateway/chat-service/script"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchHandler: GET /chat/search?q=&peer_id=&group_id=&conversation_id=
+// &from=&to=&has_attachment=&cursor=&limit=
+func searchHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	req := &chatpb.SearchMessagesRequest{
+		Query:  c.Query("q"),
+		From:   c.Query("from"),
+		To:     c.Query("to"),
+		Cursor: c.Query("cursor"),
+	}
+	if req.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query is required"})
+		return
+	}
+
+	ids := map[string]*int32{
+		"conversation_id": &req.ConversationId,
+		"peer_id":         &req.PeerId,
+		"group_id":        &req.GroupId,
+		"limit":           &req.Limit,
+	}
+	for name, field := range ids {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+			return
+		}
+		*field = int32(id)
+	}
+
+	if hasAttachment := c.Query("has_attachment"); hasAttachment != "" {
+		value, err := strconv.ParseBool(hasAttachment)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid has_attachment"})
+			return
+		}
+		req.HasAttachment = value
+	}
+
+	res, err := grpcClient.SearchMessages(ctx, req)
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search messages"})
+		return
+	}
+
+	results := []gin.H{}
+	for _, result := range res.Results {
+		results = append(results, gin.H{
+			"message": messageJSON(result.Message),
+			"snippet": result.Snippet,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":     results,
+		"next_cursor": res.NextCursor,
+		"has_more":    res.HasMore,
+	})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	chatpb "api-gateway/chat-service/script"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func syncHandler(c *gin.Context) {
+	ctx, ok := tokenContext(c)
+	if !ok {
+		return
+	}
+
+	var req chatpb.SyncRequest
+	if sinceSeq := c.Query("since_seq"); sinceSeq != "" {
+		seq, err := strconv.ParseInt(sinceSeq, 10, 64)
+		if err != nil || seq < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since_seq"})
+			return
+		}
+		req.SinceSeq = seq
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		limitInt, err := strconv.Atoi(limit)
+		if err != nil || limitInt < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		req.Limit = int32(limitInt)
+	}
+
+	res, err := grpcClient.Sync(ctx, &req)
+	if err != nil {
+		log.Print(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync events"})
+		return
+	}
+
+	// Event memiliki banyak bentuk, jadi dikirim apa adanya dalam format JSON protobuf
+	data, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(res)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode events"})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", data)
+}
@@ -0,0 +1,175 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"api-gateway/config"
+
+	"github.com/gorilla/websocket"
+)
+
+// client membungkus satu koneksi WebSocket. Semua tulisan ke koneksi
+// dilakukan oleh writeLoop; goroutine lain cukup memanggil enqueue.
+type client struct {
+	conn    *websocket.Conn
+	cfg     config.WebSocket
+	metrics *Metrics
+
+	queue chan []byte
+	done  chan struct{}
+
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+}
+
+func newClient(conn *websocket.Conn, cfg config.WebSocket, metrics *Metrics) *client {
+	cl := &client{
+		conn:    conn,
+		cfg:     cfg,
+		metrics: metrics,
+		queue:   make(chan []byte, cfg.SendQueueSize),
+		done:    make(chan struct{}),
+	}
+
+	conn.SetReadLimit(cfg.MaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(cfg.PongTimeout))
+	conn.SetPongHandler(func(string) error {
+		metrics.PongsRecv.Add(1)
+		return conn.SetReadDeadline(time.Now().Add(cfg.PongTimeout))
+	})
+
+	return cl
+}
+
+// wsConfig melengkapi nilai konfigurasi yang kosong atau tidak masuk akal
+func wsConfig(cfg config.WebSocket) config.WebSocket {
+	if cfg.PongTimeout <= 0 {
+		cfg.PongTimeout = 60 * time.Second
+	}
+	if cfg.PingInterval <= 0 || cfg.PingInterval >= cfg.PongTimeout {
+		cfg.PingInterval = cfg.PongTimeout * 9 / 10
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 10 * time.Second
+	}
+	if cfg.SendQueueSize <= 0 {
+		cfg.SendQueueSize = 64
+	}
+	if cfg.MaxMessageSize <= 0 {
+		cfg.MaxMessageSize = 64 * 1024
+	}
+	return cfg
+}
+
+// enqueue memasukkan envelope ke antrean kirim tanpa pernah menunggu.
+// Jika antrean penuh, client dianggap terlalu lambat dan koneksi diputus.
+func (cl *client) enqueue(env Envelope) bool {
+	data, err := json.Marshal(env)
+	if err != nil {
+		log.Printf("Error encoding envelope for user_id %d: %v", cl.metrics.UserID, err)
+		return true
+	}
+
+	select {
+	case <-cl.done:
+		return false
+	default:
+	}
+
+	select {
+	case cl.queue <- data:
+		cl.metrics.queued(len(cl.queue))
+		return true
+	default:
+		cl.metrics.Dropped.Add(1)
+		slowConsumerClosed.Add(1)
+		log.Printf("WebSocket send queue full for user_id %d, disconnecting slow consumer", cl.metrics.UserID)
+		cl.close(websocket.ClosePolicyViolation, "Slow consumer")
+		return false
+	}
+}
+
+// close meminta writeLoop mengirim close frame lalu menutup koneksi
+func (cl *client) close(code int, text string) {
+	cl.closeOnce.Do(func() {
+		cl.closeCode = code
+		cl.closeText = text
+		close(cl.done)
+	})
+}
+
+// read membaca satu pesan dari client dan memperpanjang batas idle
+func (cl *client) read() ([]byte, error) {
+	_, data, err := cl.conn.ReadMessage()
+	if err != nil {
+		if ne, ok := err.(interface{ Timeout() bool }); ok && ne.Timeout() {
+			pongTimeoutClosed.Add(1)
+		}
+		return nil, err
+	}
+
+	cl.metrics.FramesIn.Add(1)
+	cl.metrics.BytesIn.Add(int64(len(data)))
+	cl.conn.SetReadDeadline(time.Now().Add(cl.cfg.PongTimeout))
+	return data, nil
+}
+
+// writeLoop adalah satu-satunya penulis ke koneksi: mengirim isi antrean,
+// ping berkala, dan close frame saat client ditutup
+func (cl *client) writeLoop() {
+	ticker := time.NewTicker(cl.cfg.PingInterval)
+	defer func() {
+		ticker.Stop()
+		cl.conn.Close()
+	}()
+
+	for {
+		select {
+		case data := <-cl.queue:
+			cl.metrics.QueueDepth.Store(int64(len(cl.queue)))
+			cl.conn.SetWriteDeadline(time.Now().Add(cl.cfg.WriteTimeout))
+			if err := cl.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				log.Printf("Error writing to WebSocket for user_id %d: %v", cl.metrics.UserID, err)
+				cl.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+			cl.metrics.FramesOut.Add(1)
+			cl.metrics.BytesOut.Add(int64(len(data)))
+		case <-ticker.C:
+			if err := cl.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(cl.cfg.WriteTimeout)); err != nil {
+				log.Printf("Error sending ping for user_id %d: %v", cl.metrics.UserID, err)
+				cl.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+			cl.metrics.PingsSent.Add(1)
+		case <-cl.done:
+			if cl.closeCode != websocket.CloseAbnormalClosure {
+				cl.flush()
+				msg := websocket.FormatCloseMessage(cl.closeCode, cl.closeText)
+				cl.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(cl.cfg.WriteTimeout))
+			}
+			return
+		}
+	}
+}
+
+// flush mengirim sisa antrean sebelum close frame, dibatasi satu WriteTimeout
+func (cl *client) flush() {
+	cl.conn.SetWriteDeadline(time.Now().Add(cl.cfg.WriteTimeout))
+	for {
+		select {
+		case data := <-cl.queue:
+			if err := cl.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+			cl.metrics.FramesOut.Add(1)
+			cl.metrics.BytesOut.Add(int64(len(data)))
+		default:
+			return
+		}
+	}
+}
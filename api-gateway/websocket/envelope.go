@@ -0,0 +1,132 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+
+	pb "api-gateway/chat-service/script"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtocolVersion adalah versi envelope WebSocket, lihat docs/websocket-protocol.md
+const ProtocolVersion = 1
+
+// Envelope membungkus setiap frame WebSocket, baik dari client maupun server.
+// ID dibuat oleh client dan dikembalikan server pada frame ack/error/sync/thread
+// yang menjawab request tersebut.
+type Envelope struct {
+	V       int             `json:"v"`
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Jenis frame dari client
+const (
+	TypeSend   = "send"
+	TypeTyping = "typing"
+	TypeAck    = "ack"
+	TypeRead   = "read"
+	TypeSync   = "sync"
+	TypeThread = "thread"
+)
+
+// Jenis frame dari server selain event (message, receipt, presence, typing, ...)
+const (
+	TypeError = "error"
+)
+
+var (
+	marshaler   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	unmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// clientFrame mengubah envelope dari client menjadi ClientFrame untuk chat-service
+func clientFrame(env Envelope) (*pb.ClientFrame, error) {
+	if env.V != ProtocolVersion {
+		return nil, fmt.Errorf("unsupported protocol version %d", env.V)
+	}
+	if env.ID == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+
+	frame := &pb.ClientFrame{Id: env.ID}
+
+	var payload proto.Message
+	switch env.Type {
+	case TypeSend:
+		req := &pb.SendMessageRequest{}
+		frame.Frame, payload = &pb.ClientFrame_Send{Send: req}, req
+	case TypeTyping:
+		req := &pb.TypingFrame{}
+		frame.Frame, payload = &pb.ClientFrame_Typing{Typing: req}, req
+	case TypeAck:
+		req := &pb.AckFrame{}
+		frame.Frame, payload = &pb.ClientFrame_Ack{Ack: req}, req
+	case TypeRead:
+		req := &pb.MarkReadRequest{}
+		frame.Frame, payload = &pb.ClientFrame_Read{Read: req}, req
+	case TypeSync:
+		req := &pb.SyncRequest{}
+		frame.Frame, payload = &pb.ClientFrame_Sync{Sync: req}, req
+	case TypeThread:
+		req := &pb.ListThreadRequest{}
+		frame.Frame, payload = &pb.ClientFrame_Thread{Thread: req}, req
+	default:
+		return nil, fmt.Errorf("unknown frame type %q", env.Type)
+	}
+
+	if len(env.Payload) > 0 {
+		if err := unmarshaler.Unmarshal(env.Payload, payload); err != nil {
+			return nil, fmt.Errorf("invalid %s payload", env.Type)
+		}
+	}
+
+	return frame, nil
+}
+
+// serverEnvelope mengubah ServerFrame dari chat-service menjadi envelope.
+// Event dikirim dengan type sesuai jenis event-nya (message, typing,
+// reaction, ...), sehingga client cukup melihat field type.
+func serverEnvelope(frame *pb.ServerFrame) (Envelope, error) {
+	env := Envelope{V: ProtocolVersion, ID: frame.Id}
+
+	var payload proto.Message
+	switch f := frame.Frame.(type) {
+	case *pb.ServerFrame_Message:
+		env.Type, payload = f.Message.Type, f.Message
+		if env.Type == "" {
+			env.Type = "message"
+		}
+	case *pb.ServerFrame_Receipt:
+		env.Type, payload = "receipt", f.Receipt
+	case *pb.ServerFrame_Presence:
+		env.Type, payload = "presence", f.Presence
+	case *pb.ServerFrame_Error:
+		env.Type, payload = TypeError, f.Error
+	case *pb.ServerFrame_Ack:
+		env.Type, payload = TypeAck, f.Ack
+	case *pb.ServerFrame_Sync:
+		env.Type, payload = TypeSync, f.Sync
+	case *pb.ServerFrame_Thread:
+		env.Type, payload = TypeThread, f.Thread
+	default:
+		return env, fmt.Errorf("unknown server frame")
+	}
+
+	data, err := marshaler.Marshal(payload)
+	if err != nil {
+		return env, err
+	}
+	env.Payload = data
+
+	return env, nil
+}
+
+// errorEnvelope membuat frame error untuk request dengan id tertentu
+func errorEnvelope(id, message string) Envelope {
+	data, _ := marshaler.Marshal(&pb.ErrorFrame{Message: message})
+	return Envelope{V: ProtocolVersion, Type: TypeError, ID: id, Payload: data}
+}
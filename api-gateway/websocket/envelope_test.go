@@ -0,0 +1,169 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+
+	pb "api-gateway/chat-service/script"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestClientFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    *pb.ClientFrame
+		wantErr bool
+	}{
+		{
+			name: "send",
+			raw:  `{"v":1,"type":"send","id":"c1","payload":{"receiver_id":[2],"content":"halo","client_message_id":"m-1"}}`,
+			want: &pb.ClientFrame{Id: "c1", Frame: &pb.ClientFrame_Send{Send: &pb.SendMessageRequest{
+				ReceiverId: []int32{2}, Content: "halo", ClientMessageId: "m-1",
+			}}},
+		},
+		{
+			name: "typing",
+			raw:  `{"v":1,"type":"typing","id":"c2","payload":{"conversation_id":5,"typing":true}}`,
+			want: &pb.ClientFrame{Id: "c2", Frame: &pb.ClientFrame_Typing{Typing: &pb.TypingFrame{ConversationId: 5, Typing: true}}},
+		},
+		{
+			name: "ack",
+			raw:  `{"v":1,"type":"ack","id":"c3","payload":{"message_id":10}}`,
+			want: &pb.ClientFrame{Id: "c3", Frame: &pb.ClientFrame_Ack{Ack: &pb.AckFrame{MessageId: 10}}},
+		},
+		{
+			name: "sync without payload",
+			raw:  `{"v":1,"type":"sync","id":"c4"}`,
+			want: &pb.ClientFrame{Id: "c4", Frame: &pb.ClientFrame_Sync{Sync: &pb.SyncRequest{}}},
+		},
+		{
+			name: "unknown payload fields are ignored",
+			raw:  `{"v":1,"type":"ack","id":"c5","payload":{"message_id":10,"extra":"x"}}`,
+			want: &pb.ClientFrame{Id: "c5", Frame: &pb.ClientFrame_Ack{Ack: &pb.AckFrame{MessageId: 10}}},
+		},
+		{name: "unsupported version", raw: `{"v":2,"type":"ack","id":"c6"}`, wantErr: true},
+		{name: "missing id", raw: `{"v":1,"type":"ack"}`, wantErr: true},
+		{name: "unknown type", raw: `{"v":1,"type":"dance","id":"c7"}`, wantErr: true},
+		{name: "invalid payload", raw: `{"v":1,"type":"ack","id":"c8","payload":{"message_id":"ten"}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var env Envelope
+			if err := json.Unmarshal([]byte(tt.raw), &env); err != nil {
+				t.Fatalf("decode envelope: %v", err)
+			}
+
+			got, err := clientFrame(env)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("clientFrame error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !proto.Equal(got, tt.want) {
+				t.Errorf("clientFrame = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerEnvelope(t *testing.T) {
+	tests := []struct {
+		name     string
+		frame    *pb.ServerFrame
+		wantType string
+		wantID   string
+		payload  proto.Message // tipe payload yang diharapkan, diisi hasil decode
+		want     proto.Message
+	}{
+		{
+			name:     "event keeps its type",
+			frame:    &pb.ServerFrame{Frame: &pb.ServerFrame_Message{Message: &pb.StreamMessagesResponse{Type: "typing", SenderId: 3}}},
+			wantType: "typing",
+			payload:  &pb.StreamMessagesResponse{},
+			want:     &pb.StreamMessagesResponse{Type: "typing", SenderId: 3},
+		},
+		{
+			name:     "event without type is a message",
+			frame:    &pb.ServerFrame{Frame: &pb.ServerFrame_Message{Message: &pb.StreamMessagesResponse{MessageId: 10, Content: "halo"}}},
+			wantType: "message",
+			payload:  &pb.StreamMessagesResponse{},
+			want:     &pb.StreamMessagesResponse{MessageId: 10, Content: "halo"},
+		},
+		{
+			name:     "receipt",
+			frame:    &pb.ServerFrame{Frame: &pb.ServerFrame_Receipt{Receipt: &pb.Receipt{MessageId: 10, Status: "read"}}},
+			wantType: "receipt",
+			payload:  &pb.Receipt{},
+			want:     &pb.Receipt{MessageId: 10, Status: "read"},
+		},
+		{
+			name:     "ack answers the request id",
+			frame:    &pb.ServerFrame{Id: "c1", Frame: &pb.ServerFrame_Ack{Ack: &pb.Ack{Status: "ok", Duplicate: true}}},
+			wantType: TypeAck,
+			wantID:   "c1",
+			payload:  &pb.Ack{},
+			want:     &pb.Ack{Status: "ok", Duplicate: true},
+		},
+		{
+			name:     "error",
+			frame:    &pb.ServerFrame{Id: "c2", Frame: &pb.ServerFrame_Error{Error: &pb.ErrorFrame{Message: "boom"}}},
+			wantType: TypeError,
+			wantID:   "c2",
+			payload:  &pb.ErrorFrame{},
+			want:     &pb.ErrorFrame{Message: "boom"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, err := serverEnvelope(tt.frame)
+			if err != nil {
+				t.Fatalf("serverEnvelope: %v", err)
+			}
+
+			// Envelope dikirim sebagai JSON, jadi periksa hasil setelah encode ulang
+			data, err := json.Marshal(env)
+			if err != nil {
+				t.Fatalf("encode envelope: %v", err)
+			}
+			var decoded Envelope
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("decode envelope: %v", err)
+			}
+
+			if decoded.V != ProtocolVersion || decoded.Type != tt.wantType || decoded.ID != tt.wantID {
+				t.Errorf("envelope = v%d %q id %q, want v%d %q id %q",
+					decoded.V, decoded.Type, decoded.ID, ProtocolVersion, tt.wantType, tt.wantID)
+			}
+
+			if err := unmarshaler.Unmarshal(decoded.Payload, tt.payload); err != nil {
+				t.Fatalf("decode payload: %v", err)
+			}
+			if !proto.Equal(tt.payload, tt.want) {
+				t.Errorf("payload = %v, want %v", tt.payload, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerEnvelopeUnknownFrame(t *testing.T) {
+	if _, err := serverEnvelope(&pb.ServerFrame{Id: "c1"}); err == nil {
+		t.Error("serverEnvelope accepted a frame without content")
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	env := errorEnvelope("c9", "invalid frame")
+	if env.V != ProtocolVersion || env.Type != TypeError || env.ID != "c9" {
+		t.Fatalf("errorEnvelope = v%d %q id %q", env.V, env.Type, env.ID)
+	}
+
+	var payload pb.ErrorFrame
+	if err := unmarshaler.Unmarshal(env.Payload, &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if payload.Message != "invalid frame" {
+		t.Errorf("message = %q, want %q", payload.Message, "invalid frame")
+	}
+}
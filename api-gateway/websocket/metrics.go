@@ -0,0 +1,122 @@
+package websocket
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics mencatat statistik satu koneksi WebSocket
+type Metrics struct {
+	ID          int64
+	UserID      int
+	DeviceID    string
+	ConnectedAt time.Time
+
+	FramesIn   atomic.Int64
+	FramesOut  atomic.Int64
+	BytesIn    atomic.Int64
+	BytesOut   atomic.Int64
+	PingsSent  atomic.Int64
+	PongsRecv  atomic.Int64
+	QueueDepth atomic.Int64
+	QueuePeak  atomic.Int64
+	Dropped    atomic.Int64
+}
+
+var (
+	connections sync.Map // id -> *Metrics
+	nextConnID  atomic.Int64
+
+	totalConnections   = expvar.NewInt("websocket_connections_total")
+	activeConnections  = expvar.NewInt("websocket_connections_active")
+	slowConsumerClosed = expvar.NewInt("websocket_slow_consumer_disconnects")
+	pongTimeoutClosed  = expvar.NewInt("websocket_pong_timeout_disconnects")
+)
+
+func init() {
+	expvar.Publish("websocket_traffic", expvar.Func(func() any {
+		return Aggregate()
+	}))
+}
+
+func newMetrics(userID int, deviceID string) *Metrics {
+	m := &Metrics{
+		ID:          nextConnID.Add(1),
+		UserID:      userID,
+		DeviceID:    deviceID,
+		ConnectedAt: time.Now(),
+	}
+	connections.Store(m.ID, m)
+	totalConnections.Add(1)
+	activeConnections.Add(1)
+	return m
+}
+
+func (m *Metrics) release() {
+	connections.Delete(m.ID)
+	activeConnections.Add(-1)
+}
+
+// queued dipanggil setelah frame masuk antrean, untuk mencatat kedalaman tertinggi
+func (m *Metrics) queued(depth int) {
+	d := int64(depth)
+	m.QueueDepth.Store(d)
+	for {
+		peak := m.QueuePeak.Load()
+		if d <= peak || m.QueuePeak.CompareAndSwap(peak, d) {
+			return
+		}
+	}
+}
+
+func (m *Metrics) snapshot() map[string]any {
+	return map[string]any{
+		"id":           m.ID,
+		"user_id":      m.UserID,
+		"device_id":    m.DeviceID,
+		"connected_at": m.ConnectedAt.Format(time.RFC3339),
+		"frames_in":    m.FramesIn.Load(),
+		"frames_out":   m.FramesOut.Load(),
+		"bytes_in":     m.BytesIn.Load(),
+		"bytes_out":    m.BytesOut.Load(),
+		"pings_sent":   m.PingsSent.Load(),
+		"pongs_recv":   m.PongsRecv.Load(),
+		"queue_depth":  m.QueueDepth.Load(),
+		"queue_peak":   m.QueuePeak.Load(),
+		"dropped":      m.Dropped.Load(),
+	}
+}
+
+// Aggregate menjumlahkan statistik semua koneksi yang sedang aktif tanpa
+// identitas user atau device, dipublikasikan lewat expvar sebagai "websocket_traffic"
+func Aggregate() map[string]int64 {
+	result := map[string]int64{
+		"connections": 0,
+		"frames_in":   0,
+		"frames_out":  0,
+		"bytes_in":    0,
+		"bytes_out":   0,
+		"pings_sent":  0,
+		"pongs_recv":  0,
+		"queue_depth": 0,
+		"queue_peak":  0,
+		"dropped":     0,
+	}
+	connections.Range(func(_, v any) bool {
+		m := v.(*Metrics)
+		result["connections"]++
+		result["frames_in"] += m.FramesIn.Load()
+		result["frames_out"] += m.FramesOut.Load()
+		result["bytes_in"] += m.BytesIn.Load()
+		result["bytes_out"] += m.BytesOut.Load()
+		result["pings_sent"] += m.PingsSent.Load()
+		result["pongs_recv"] += m.PongsRecv.Load()
+		result["queue_depth"] += m.QueueDepth.Load()
+		result["queue_peak"] = max(result["queue_peak"], m.QueuePeak.Load())
+		result["dropped"] += m.Dropped.Load()
+		return true
+	})
+	return result
+}
@@ -2,6 +2,7 @@ package websocket
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -9,30 +10,30 @@ import (
 	"sync"
 
 	pb "api-gateway/chat-service/script"
+	"api-gateway/config"
 	"api-gateway/helper"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v8"
-
 	"github.com/gorilla/websocket"
 	"google.golang.org/grpc/metadata"
 )
 
-var redisClient = redis.NewClient(&redis.Options{
-	Addr: "localhost:6379",
-})
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
 
-var (
-	upgrader = websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
-	}
-	ClientsMutex sync.Mutex
-	grpcClient   pb.ChatServiceClient
-)
+// closeWithError mengirim frame error lalu menutup koneksi,
+// dipakai sebelum stream ke chat-service terbentuk
+func closeWithError(conn *websocket.Conn, code int, message string) {
+	data, _ := json.Marshal(errorEnvelope("", message))
+	conn.WriteMessage(websocket.TextMessage, data)
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, message))
+}
 
-func WsHandler(grpcClient pb.ChatServiceClient) gin.HandlerFunc {
+func WsHandler(grpcClient pb.ChatServiceClient, cfg config.WebSocket) gin.HandlerFunc {
+	cfg = wsConfig(cfg)
 
 	return func(c *gin.Context) {
 
@@ -46,14 +47,14 @@ func WsHandler(grpcClient pb.ChatServiceClient) gin.HandlerFunc {
 		token := c.GetHeader("token")
 		if token == "" {
 			log.Println("Missing token header")
-			conn.WriteMessage(websocket.TextMessage, []byte("Unauthorized"))
+			closeWithError(conn, websocket.ClosePolicyViolation, "Unauthorized")
 			return
 		}
 
 		senderID, err := helper.GetIdFromJWT1(token)
 		if err != nil {
 			fmt.Printf("Failed to extract sender ID from token: %v", err)
-			conn.WriteMessage(websocket.TextMessage, []byte("Invalid token"))
+			closeWithError(conn, websocket.ClosePolicyViolation, "Invalid token")
 			return
 		}
 
@@ -61,7 +62,7 @@ func WsHandler(grpcClient pb.ChatServiceClient) gin.HandlerFunc {
 
 		if err := updateUserIsOnlineStatus(userID, true); err != nil {
 			log.Printf("Failed to update user online status for user_id %d: %v", userID, err)
-			conn.WriteMessage(websocket.TextMessage, []byte("Failed to update online status"))
+			closeWithError(conn, websocket.CloseInternalServerErr, "Failed to update online status")
 			return
 		}
 
@@ -71,106 +72,106 @@ func WsHandler(grpcClient pb.ChatServiceClient) gin.HandlerFunc {
 			}
 		}()
 
-		redisKey := fmt.Sprintf("user:%d:offline_messages", userID)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
 
-		offlineMessages, err := redisClient.LRange(context.Background(), redisKey, 0, -1).Result()
-		if err != nil {
-			log.Printf("Error retrieving offline messages for user_id %d: %v", userID, err)
-		} else {
-			for _, msg := range offlineMessages {
-				if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
-					log.Printf("Error sending offline message to user_id %d: %v", userID, err)
-				}
-			}
+		md := metadata.Pairs("token", token)
 
-			if err := redisClient.Del(context.Background(), redisKey).Err(); err != nil {
-				log.Printf("Error deleting offline messages for user_id %d: %v", userID, err)
-			}
+		// device_id membedakan beberapa device milik user yang sama
+		deviceID := c.Query("device_id")
+		if deviceID == "" {
+			deviceID = c.GetHeader("device_id")
 		}
+		if deviceID != "" {
+			md.Set("device_id", deviceID)
+		}
+		ctx = metadata.NewOutgoingContext(ctx, md)
 
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
+		stream, err := grpcClient.Chat(ctx)
+		if err != nil {
+			log.Printf("Error starting gRPC stream for user_id %d: %v", userID, err)
+			closeWithError(conn, websocket.CloseInternalServerErr, "Failed to connect to chat service")
+			return
+		}
+
+		metrics := newMetrics(userID, deviceID)
+		defer func() {
+			metrics.release()
+			log.Printf("WebSocket closed for user_id %d: %v", userID, metrics.snapshot())
+		}()
+
+		cl := newClient(conn, cfg, metrics)
 
 		var wg sync.WaitGroup
 
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			md := metadata.Pairs("token", token)
-			ctx = metadata.NewOutgoingContext(ctx, md)
-
-			stream, err := grpcClient.StreamMessages(ctx, &pb.StreamMessagesRequest{
-				Token: token,
-			})
-
-			if err != nil {
-				log.Printf("Error starting gRPC stream for user_id %d: %v", userID, err)
-				return
-			}
+			cl.writeLoop()
+		}()
 
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
 			for {
-				resp, err := stream.Recv()
+				frame, err := stream.Recv()
 				if err != nil {
-					log.Printf("Error receiving gRPC message for user_id %d: %v", userID, err)
+					log.Printf("Error receiving gRPC frame for user_id %d: %v", userID, err)
 					cancel()
-					break
+					cl.close(websocket.CloseInternalServerErr, "Chat service stream closed")
+					return
+				}
+
+				env, err := serverEnvelope(frame)
+				if err != nil {
+					log.Printf("Error encoding frame for user_id %d: %v", userID, err)
+					continue
 				}
 
-				if err := conn.WriteJSON(resp); err != nil {
-					log.Printf("Error writing to WebSocket: %v", err)
+				if !cl.enqueue(env) {
 					cancel()
-					break
+					return
 				}
 			}
 		}()
 
+		// Setiap pesan WebSocket adalah Envelope JSON, misalnya
+		// {"v":1,"type":"send","id":"c1","payload":{"receiver_id":[2],"content":"halo"}}
+		// Jawaban ack/error memakai id yang sama. Typing cukup dikirim ulang
+		// selama user masih mengetik; chat-service yang melakukan throttle dan
+		// mengirim typing false jika tidak ada pembaruan.
+		// Server mengirim ping tiap WS_PING_INTERVAL_SECONDS; client yang tidak
+		// membalas pong (atau mengirim pesan) dalam WS_PONG_TIMEOUT_SECONDS diputus.
+		// Skema lengkap ada di docs/websocket-protocol.md.
 		for {
-			var rawMsg map[string]interface{}
-			if err := conn.ReadJSON(&rawMsg); err != nil {
+			data, err := cl.read()
+			if err != nil {
 				log.Printf("Error reading WebSocket message: %v", err)
-				cancel()
 				break
 			}
 
-			var msg pb.SendMessageRequest
-			if content, ok := rawMsg["content"].(string); ok {
-				msg.Content = content
-			}
-
-			receiverIDRaw := rawMsg["receiver_id"]
-
-			switch v := receiverIDRaw.(type) {
-			case float64:
-				msg.ReceiverId = []int32{int32(v)}
-			case []interface{}:
-				for _, id := range v {
-					if idFloat, ok := id.(float64); ok {
-						msg.ReceiverId = append(msg.ReceiverId, int32(idFloat))
-					}
-				}
-			default:
-				log.Println("Invalid receiver_id format")
-				conn.WriteMessage(websocket.TextMessage, []byte("Invalid receiver_id format"))
+			var env Envelope
+			if err := json.Unmarshal(data, &env); err != nil {
+				cl.enqueue(errorEnvelope("", "Invalid frame format"))
 				continue
 			}
 
-			log.Printf("Message from WebSocket: %s", msg.Content)
-
-			if len(msg.ReceiverId) == 0 {
-				log.Println("No receiver IDs provided")
-				conn.WriteMessage(websocket.TextMessage, []byte("No receiver IDs provided"))
+			frame, err := clientFrame(env)
+			if err != nil {
+				cl.enqueue(errorEnvelope(env.ID, err.Error()))
 				continue
 			}
 
-			for _, receiverID := range msg.ReceiverId {
-				log.Printf("Forwarding message to receiver ID %d", receiverID)
-				if err := helper.ForwardToGrpc(msg.Content, []int{int(receiverID)}, token); err != nil {
-					log.Printf("Error forwarding message to receiver %d: %v", receiverID, err)
-					conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("Failed to forward message to receiver %d", receiverID)))
-					continue
-				}
+			if err := stream.Send(frame); err != nil {
+				log.Printf("Error sending frame to chat service for user_id %d: %v", userID, err)
+				break
 			}
 		}
+
+		stream.CloseSend()
+		cancel()
+		cl.close(websocket.CloseNormalClosure, "")
+		wg.Wait()
 	}
 }
 
@@ -0,0 +1,21 @@
+package bus
+
+import (
+	pb "chat-service/proto/script"
+	"context"
+)
+
+// Bus meneruskan event ke stream user di semua replica chat-service
+type Bus interface {
+	// Publish mengirim event ke user dan mengembalikan jumlah subscriber
+	// yang menerimanya. Nilai 0 berarti user tidak punya stream di replica manapun.
+	Publish(ctx context.Context, userID int, event *pb.StreamMessagesResponse) (int, error)
+
+	// Subscribe mulai menerima event milik user di replica ini
+	Subscribe(ctx context.Context, userID int) (Subscription, error)
+}
+
+type Subscription interface {
+	Events() <-chan *pb.StreamMessagesResponse
+	Close() error
+}
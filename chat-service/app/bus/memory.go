@@ -0,0 +1,104 @@
+package bus
+
+import (
+	pb "chat-service/proto/script"
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// MemoryBus adalah Bus di dalam satu proses, dipakai untuk test
+// dan untuk menjalankan satu replica tanpa Redis
+type MemoryBus struct {
+	mu   sync.Mutex
+	subs map[int]map[*memorySubscription]struct{}
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subs: make(map[int]map[*memorySubscription]struct{})}
+}
+
+// Publish tidak pernah menunggu subscriber. Event untuk subscription yang
+// buffernya penuh dibuang, sama seperti Redis pub/sub; event tersimpan tetap
+// bisa diambil lewat replay dan Sync.
+func (b *MemoryBus) Publish(ctx context.Context, userID int, event *pb.StreamMessagesResponse) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	b.mu.Lock()
+	subs := make([]*memorySubscription, 0, len(b.subs[userID]))
+	for sub := range b.subs[userID] {
+		subs = append(subs, sub)
+	}
+	b.mu.Unlock()
+
+	received := 0
+	for _, sub := range subs {
+		select {
+		case <-sub.done:
+			continue
+		default:
+		}
+
+		select {
+		case sub.events <- event:
+			sub.overflow.Store(false)
+			received++
+		default:
+			if !sub.overflow.Swap(true) {
+				log.Printf("Event bus buffer of user %d is full, dropping events", userID)
+			}
+		}
+	}
+
+	return received, nil
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context, userID int) (Subscription, error) {
+	sub := &memorySubscription{
+		bus:    b,
+		userID: userID,
+		events: make(chan *pb.StreamMessagesResponse, 10),
+		done:   make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[*memorySubscription]struct{})
+	}
+	b.subs[userID][sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub, nil
+}
+
+type memorySubscription struct {
+	bus       *MemoryBus
+	userID    int
+	events    chan *pb.StreamMessagesResponse
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// overflow bernilai true selama buffer penuh, agar log hanya ditulis sekali
+	overflow atomic.Bool
+}
+
+func (s *memorySubscription) Events() <-chan *pb.StreamMessagesResponse {
+	return s.events
+}
+
+func (s *memorySubscription) Close() error {
+	s.closeOnce.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs[s.userID], s)
+		if len(s.bus.subs[s.userID]) == 0 {
+			delete(s.bus.subs, s.userID)
+		}
+		s.bus.mu.Unlock()
+
+		close(s.done)
+	})
+	return nil
+}
@@ -0,0 +1,128 @@
+package bus
+
+import (
+	pb "chat-service/proto/script"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryBusPublish(t *testing.T) {
+	tests := []struct {
+		name      string
+		subscribe []int // user id per subscription, satu subscription per replica
+		close     []int // index subscription yang ditutup sebelum publish
+		publishTo int
+		want      []int // index subscription yang harus menerima event
+	}{
+		{name: "single replica", subscribe: []int{1}, publishTo: 1, want: []int{0}},
+		{name: "fan out to every replica", subscribe: []int{1, 1, 1}, publishTo: 1, want: []int{0, 1, 2}},
+		{name: "other users do not receive", subscribe: []int{1, 2}, publishTo: 1, want: []int{0}},
+		{name: "closed subscription is skipped", subscribe: []int{1, 1}, close: []int{0}, publishTo: 1, want: []int{1}},
+		{name: "no subscriber", subscribe: []int{2}, publishTo: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewMemoryBus()
+			ctx := context.Background()
+
+			subs := make([]Subscription, len(tt.subscribe))
+			for i, userID := range tt.subscribe {
+				sub, err := b.Subscribe(ctx, userID)
+				if err != nil {
+					t.Fatalf("subscribe: %v", err)
+				}
+				defer sub.Close()
+				subs[i] = sub
+			}
+			for _, i := range tt.close {
+				subs[i].Close()
+			}
+
+			n, err := b.Publish(ctx, tt.publishTo, &pb.StreamMessagesResponse{Type: "message", MessageId: 7})
+			if err != nil {
+				t.Fatalf("publish: %v", err)
+			}
+			if n != len(tt.want) {
+				t.Fatalf("publish reached %d subscribers, want %d", n, len(tt.want))
+			}
+
+			for i, sub := range subs {
+				wantEvent := false
+				for _, w := range tt.want {
+					wantEvent = wantEvent || w == i
+				}
+
+				select {
+				case event := <-sub.Events():
+					if !wantEvent {
+						t.Errorf("subscription %d received an unexpected event", i)
+					} else if event.MessageId != 7 {
+						t.Errorf("subscription %d received message %d, want 7", i, event.MessageId)
+					}
+				case <-time.After(50 * time.Millisecond):
+					if wantEvent {
+						t.Errorf("subscription %d did not receive the event", i)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryBusPublishDoesNotBlock(t *testing.T) {
+	b := NewMemoryBus()
+	ctx := context.Background()
+
+	slow, _ := b.Subscribe(ctx, 1)
+	defer slow.Close()
+
+	capacity := cap(slow.(*memorySubscription).events)
+	done := make(chan int)
+	go func() {
+		received := 0
+		for i := 0; i < capacity+5; i++ {
+			n, err := b.Publish(ctx, 1, &pb.StreamMessagesResponse{Type: "message", MessageId: int32(i + 1)})
+			if err != nil {
+				t.Errorf("publish: %v", err)
+			}
+			received += n
+		}
+		done <- received
+	}()
+
+	select {
+	case received := <-done:
+		if received != capacity {
+			t.Fatalf("%d events were delivered, want %d", received, capacity)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a full subscription")
+	}
+
+	// Setelah buffer dibaca event berikutnya kembali diterima
+	<-slow.Events()
+	if n, _ := b.Publish(ctx, 1, &pb.StreamMessagesResponse{Type: "message"}); n != 1 {
+		t.Fatalf("publish after draining reached %d subscribers, want 1", n)
+	}
+}
+
+func TestMemoryBusCloseCleansUp(t *testing.T) {
+	b := NewMemoryBus()
+	ctx := context.Background()
+
+	first, _ := b.Subscribe(ctx, 1)
+	second, _ := b.Subscribe(ctx, 1)
+
+	first.Close()
+	if got := len(b.subs[1]); got != 1 {
+		t.Fatalf("after closing one subscription user has %d subscriptions, want 1", got)
+	}
+
+	second.Close()
+	second.Close() // Close boleh dipanggil berulang
+	if _, ok := b.subs[1]; ok {
+		t.Fatal("user is still registered after closing every subscription")
+	}
+}
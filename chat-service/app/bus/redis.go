@@ -0,0 +1,98 @@
+package bus
+
+import (
+	pb "chat-service/proto/script"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/protobuf/proto"
+)
+
+// RedisBus memakai Redis pub/sub sehingga event yang dipublish di satu
+// replica sampai ke stream user yang terhubung ke replica lain
+type RedisBus struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+func NewRedisBus(rdb *redis.Client, prefix string) *RedisBus {
+	return &RedisBus{rdb: rdb, prefix: prefix}
+}
+
+func (b *RedisBus) channel(userID int) string {
+	return fmt.Sprintf("%s:chat:user:%d", b.prefix, userID)
+}
+
+func (b *RedisBus) Publish(ctx context.Context, userID int, event *pb.StreamMessagesResponse) (int, error) {
+	data, err := proto.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %v", err)
+	}
+
+	receivers, err := b.rdb.Publish(ctx, b.channel(userID), data).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish event: %v", err)
+	}
+
+	return int(receivers), nil
+}
+
+func (b *RedisBus) Subscribe(ctx context.Context, userID int) (Subscription, error) {
+	pubsub := b.rdb.Subscribe(ctx, b.channel(userID))
+
+	// Tunggu konfirmasi subscribe agar event setelah ini tidak terlewat
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe: %v", err)
+	}
+
+	sub := &redisSubscription{
+		pubsub: pubsub,
+		events: make(chan *pb.StreamMessagesResponse, 10),
+		done:   make(chan struct{}),
+	}
+	go sub.run()
+
+	return sub, nil
+}
+
+type redisSubscription struct {
+	pubsub    *redis.PubSub
+	events    chan *pb.StreamMessagesResponse
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *redisSubscription) run() {
+	defer close(s.events)
+
+	for msg := range s.pubsub.Channel() {
+		var event pb.StreamMessagesResponse
+		if err := proto.Unmarshal([]byte(msg.Payload), &event); err != nil {
+			log.Printf("Error decoding event from %s: %v", msg.Channel, err)
+			continue
+		}
+
+		select {
+		case s.events <- &event:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *redisSubscription) Events() <-chan *pb.StreamMessagesResponse {
+	return s.events
+}
+
+func (s *redisSubscription) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.pubsub.Close()
+	})
+	return err
+}
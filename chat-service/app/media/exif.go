@@ -0,0 +1,197 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+)
+
+const (
+	tagOrientation = 0x0112
+	tagGPSInfo     = 0x8825
+)
+
+// StripLocation menghapus data lokasi (GPS IFD) dari EXIF gambar JPEG atau
+// PNG tanpa meng-encode ulang gambar. Metadata lain seperti orientasi tetap
+// dipertahankan. Mengembalikan data baru, nilai orientasi EXIF (1 jika tidak
+// ada) dan apakah data berubah. Format lain (misalnya GIF yang tidak memiliki
+// EXIF) dikembalikan apa adanya.
+func StripLocation(data []byte, mimeType string) ([]byte, int, bool) {
+	switch mimeType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	default:
+		return data, 1, false
+	}
+}
+
+// stripJPEG mencari segmen APP1 berisi EXIF dan membersihkan GPS IFD-nya
+func stripJPEG(data []byte) ([]byte, int, bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return data, 1, false
+	}
+
+	out := append([]byte(nil), data...)
+	orientation, changed := 1, false
+
+	for i := 2; i+4 <= len(out); {
+		if out[i] != 0xFF {
+			break
+		}
+		marker := out[i+1]
+		// SOS: data gambar dimulai, tidak ada metadata lagi setelahnya
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+
+		length := int(binary.BigEndian.Uint16(out[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(out) {
+			break
+		}
+
+		segment := out[i+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			o, c := stripTIFF(segment[6:])
+			orientation = o
+			changed = changed || c
+		}
+
+		i = end
+	}
+
+	if !changed {
+		return data, orientation, false
+	}
+	return out, orientation, true
+}
+
+// stripPNG membersihkan chunk eXIf dan menghitung ulang CRC-nya
+func stripPNG(data []byte) ([]byte, int, bool) {
+	signature := []byte("\x89PNG\r\n\x1a\n")
+	if !bytes.HasPrefix(data, signature) {
+		return data, 1, false
+	}
+
+	out := append([]byte(nil), data...)
+	orientation, changed := 1, false
+
+	for i := len(signature); i+12 <= len(out); {
+		length := int(binary.BigEndian.Uint32(out[i:]))
+		end := i + 12 + length
+		if length < 0 || end > len(out) {
+			break
+		}
+
+		if string(out[i+4:i+8]) == "eXIf" {
+			o, c := stripTIFF(out[i+8 : i+8+length])
+			orientation = o
+			if c {
+				changed = true
+				binary.BigEndian.PutUint32(out[i+8+length:], crc32.ChecksumIEEE(out[i+4:i+8+length]))
+			}
+		}
+
+		i = end
+	}
+
+	if !changed {
+		return data, orientation, false
+	}
+	return out, orientation, true
+}
+
+// stripTIFF mengosongkan GPS IFD di dalam struktur TIFF EXIF secara in-place,
+// sehingga offset data lain tidak bergeser
+func stripTIFF(tiff []byte) (int, bool) {
+	if len(tiff) < 8 {
+		return 1, false
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1, false
+	}
+
+	orientation := 1
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return orientation, false
+	}
+
+	changed := false
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			break
+		}
+
+		switch order.Uint16(tiff[entry:]) {
+		case tagOrientation:
+			orientation = int(order.Uint16(tiff[entry+8:]))
+		case tagGPSInfo:
+			gps := int(order.Uint32(tiff[entry+8:]))
+			if clearIFD(tiff, gps, order) {
+				changed = true
+			}
+		}
+	}
+
+	if orientation < 1 || orientation > 8 {
+		orientation = 1
+	}
+	return orientation, changed
+}
+
+// clearIFD menghapus isi semua entry dalam IFD beserta data di luar entry
+func clearIFD(tiff []byte, offset int, order binary.ByteOrder) bool {
+	if offset <= 0 || offset+2 > len(tiff) {
+		return false
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+	if offset+2+count*12 > len(tiff) {
+		return false
+	}
+
+	for n := 0; n < count; n++ {
+		entry := tiff[offset+2+n*12 : offset+2+(n+1)*12]
+
+		size := typeSize(order.Uint16(entry[2:])) * int(order.Uint32(entry[4:]))
+		if size > 4 {
+			start := int(order.Uint32(entry[8:]))
+			if start > 0 && size > 0 && start+size <= len(tiff) {
+				clear(tiff[start : start+size])
+			}
+		}
+
+		clear(entry)
+	}
+
+	// IFD kosong; pointer IFD berikutnya ikut terbaca nol dari entry yang dibersihkan
+	order.PutUint16(tiff[offset:], 0)
+	return count > 0
+}
+
+func typeSize(t uint16) int {
+	switch t {
+	case 1, 2, 6, 7: // BYTE, ASCII, SBYTE, UNDEFINED
+		return 1
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11: // LONG, SLONG, FLOAT
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	default:
+		return 0
+	}
+}
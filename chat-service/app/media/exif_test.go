@@ -0,0 +1,167 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+// Offset di dalam TIFF buatan exifTIFF
+const (
+	testGPSIFD  = 38
+	testGPSData = 56
+	testTIFFLen = 80
+)
+
+// exifTIFF membuat TIFF little-endian berisi IFD0 dengan orientasi dan,
+// jika withGPS, pointer ke GPS IFD yang memuat GPSLatitude (3 RATIONAL di
+// luar entry)
+func exifTIFF(orientation uint16, withGPS bool) []byte {
+	tiff := make([]byte, testTIFFLen)
+	order := binary.LittleEndian
+	copy(tiff, "II")
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+
+	entries := 1
+	if withGPS {
+		entries = 2
+	}
+	order.PutUint16(tiff[8:], uint16(entries))
+
+	entry := tiff[10:22]
+	order.PutUint16(entry[0:], tagOrientation)
+	order.PutUint16(entry[2:], 3) // SHORT
+	order.PutUint32(entry[4:], 1)
+	order.PutUint16(entry[8:], orientation)
+
+	if !withGPS {
+		return tiff
+	}
+
+	entry = tiff[22:34]
+	order.PutUint16(entry[0:], tagGPSInfo)
+	order.PutUint16(entry[2:], 4) // LONG
+	order.PutUint32(entry[4:], 1)
+	order.PutUint32(entry[8:], testGPSIFD)
+
+	order.PutUint16(tiff[testGPSIFD:], 1)
+	entry = tiff[testGPSIFD+2 : testGPSIFD+14]
+	order.PutUint16(entry[0:], 2) // GPSLatitude
+	order.PutUint16(entry[2:], 5) // RATIONAL
+	order.PutUint32(entry[4:], 3)
+	order.PutUint32(entry[8:], testGPSData)
+
+	for i := testGPSData; i < testTIFFLen; i += 4 {
+		order.PutUint32(tiff[i:], uint32(i))
+	}
+
+	return tiff
+}
+
+func jpegWithEXIF(tiff []byte) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0xFF, 0xD8, 0xFF, 0xE1})
+	binary.Write(&b, binary.BigEndian, uint16(2+6+len(tiff)))
+	b.WriteString("Exif\x00\x00")
+	b.Write(tiff)
+	b.Write([]byte{0xFF, 0xDA, 0x00, 0x02, 0x11, 0x22, 0xFF, 0xD9})
+	return b.Bytes()
+}
+
+func pngChunk(b *bytes.Buffer, kind string, data []byte) {
+	binary.Write(b, binary.BigEndian, uint32(len(data)))
+	b.WriteString(kind)
+	b.Write(data)
+	binary.Write(b, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(kind), data...)))
+}
+
+func pngWithEXIF(tiff []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("\x89PNG\r\n\x1a\n")
+	pngChunk(&b, "IHDR", make([]byte, 13))
+	pngChunk(&b, "eXIf", tiff)
+	pngChunk(&b, "IEND", nil)
+	return b.Bytes()
+}
+
+// tiffIn mengambil kembali TIFF dari hasil StripLocation
+func tiffIn(t *testing.T, data []byte, mimeType string) []byte {
+	t.Helper()
+	var start int
+	switch mimeType {
+	case "image/jpeg":
+		start = 4 + 2 + 6
+	case "image/png":
+		start = 8 + (12 + 13) + 8
+	}
+	if len(data) < start+testTIFFLen {
+		t.Fatalf("output is too short: %d bytes", len(data))
+	}
+	return data[start : start+testTIFFLen]
+}
+
+func TestStripLocation(t *testing.T) {
+	tests := []struct {
+		name            string
+		mimeType        string
+		data            []byte
+		wantOrientation int
+		wantChanged     bool
+	}{
+		{name: "jpeg with gps", mimeType: "image/jpeg", data: jpegWithEXIF(exifTIFF(6, true)), wantOrientation: 6, wantChanged: true},
+		{name: "png with gps", mimeType: "image/png", data: pngWithEXIF(exifTIFF(3, true)), wantOrientation: 3, wantChanged: true},
+		{name: "jpeg without gps", mimeType: "image/jpeg", data: jpegWithEXIF(exifTIFF(8, false)), wantOrientation: 8},
+		{name: "invalid orientation falls back to 1", mimeType: "image/jpeg", data: jpegWithEXIF(exifTIFF(42, false)), wantOrientation: 1},
+		{name: "not a jpeg", mimeType: "image/jpeg", data: []byte("hello world"), wantOrientation: 1},
+		{name: "gif has no exif", mimeType: "image/gif", data: []byte("GIF89a...."), wantOrientation: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := append([]byte(nil), tt.data...)
+
+			out, orientation, changed := StripLocation(tt.data, tt.mimeType)
+			if orientation != tt.wantOrientation {
+				t.Errorf("orientation = %d, want %d", orientation, tt.wantOrientation)
+			}
+			if changed != tt.wantChanged {
+				t.Fatalf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if !bytes.Equal(tt.data, original) {
+				t.Fatal("input was modified in place")
+			}
+			if !changed {
+				if !bytes.Equal(out, tt.data) {
+					t.Error("unchanged output differs from input")
+				}
+				return
+			}
+
+			if len(out) != len(tt.data) {
+				t.Fatalf("output has %d bytes, want %d", len(out), len(tt.data))
+			}
+
+			tiff := tiffIn(t, out, tt.mimeType)
+			if n := binary.LittleEndian.Uint16(tiff[testGPSIFD:]); n != 0 {
+				t.Errorf("gps ifd still has %d entries", n)
+			}
+			if gps := tiff[testGPSIFD:]; !bytes.Equal(gps, make([]byte, len(gps))) {
+				t.Errorf("gps data was not cleared: %x", gps)
+			}
+			if !bytes.Equal(tiff[:testGPSIFD], exifTIFF(uint16(tt.wantOrientation), true)[:testGPSIFD]) {
+				t.Error("data outside the gps ifd changed")
+			}
+
+			if tt.mimeType == "image/png" {
+				start := 8 + (12 + 13)
+				length := int(binary.BigEndian.Uint32(out[start:]))
+				chunk := out[start+4 : start+8+length]
+				if got := binary.BigEndian.Uint32(out[start+8+length:]); got != crc32.ChecksumIEEE(chunk) {
+					t.Errorf("eXIf crc = %08x, want %08x", got, crc32.ChecksumIEEE(chunk))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+const (
+	ThumbnailMimeType = "image/jpeg"
+
+	// maxPixels membatasi ukuran gambar yang mau di-decode agar file kecil
+	// dengan dimensi raksasa tidak menghabiskan memori
+	maxPixels = 40_000_000
+)
+
+type Result struct {
+	Width     int
+	Height    int
+	Thumbnail []byte
+}
+
+// Process membaca gambar PNG, JPEG atau GIF (frame pertama), mencatat
+// dimensinya sesuai orientasi EXIF dan membuat thumbnail JPEG dengan sisi
+// terpanjang maxSize piksel
+func Process(r io.Reader, orientation, maxSize int) (*Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %v", err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("image is too large (%dx%d)", cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	thumb := orient(resize(img, maxSize), orientation)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %v", err)
+	}
+
+	result := &Result{Width: cfg.Width, Height: cfg.Height, Thumbnail: buf.Bytes()}
+	if orientation >= 5 {
+		result.Width, result.Height = cfg.Height, cfg.Width
+	}
+	return result, nil
+}
+
+// resize mengecilkan gambar dengan rata-rata area (box filter) di atas
+// latar putih, sehingga gambar transparan tetap terlihat wajar sebagai JPEG
+func resize(src image.Image, maxSize int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	scale := 1.0
+	if w > maxSize || h > maxSize {
+		if w >= h {
+			scale = float64(maxSize) / float64(w)
+		} else {
+			scale = float64(maxSize) / float64(h)
+		}
+	}
+
+	dw, dh := max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale))
+
+	flat := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, b.Min, draw.Over)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+
+			var r, g, bl, n int
+			for sy := y0; sy < y1; sy++ {
+				row := flat.Pix[sy*flat.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4:]
+					r, g, bl, n = r+int(p[0]), g+int(p[1]), bl+int(p[2]), n+1
+				}
+			}
+
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0], d[1], d[2], d[3] = uint8(r/n), uint8(g/n), uint8(bl/n), 0xFF
+		}
+	}
+
+	return dst
+}
+
+// orient memutar atau membalik gambar sesuai tag Orientation EXIF (1-8)
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], src.Pix[y*src.Stride+x*4:])
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+const (
+	AttachmentPending    = "pending"
+	AttachmentProcessing = "processing"
+	AttachmentReady      = "ready"
+	AttachmentFailed     = "failed"
+)
+
+// Attachment adalah metadata file yang diunggah. Isi file disimpan di
+// BlobStore dengan StorageKey; MessageID bernilai 0 sampai file dipakai
+// di sebuah pesan.
+type Attachment struct {
+	ID         int    `gorm:"primaryKey" json:"id"`
+	MessageID  int    `gorm:"index;not null;default:0" json:"message_id"`
+	UploaderID int    `gorm:"index" json:"uploader_id"`
+	Name       string `json:"name"`
+	MimeType   string `json:"mime_type"`
+	Size       int64  `json:"size"`
+	Checksum   string `json:"checksum"` // sha256 dalam hex
+	StorageKey string `gorm:"index" json:"-"`
+	// Gambar diproses worker: dimensi, thumbnail dan penghapusan lokasi EXIF.
+	// Status kosong berarti lampiran bukan gambar yang perlu diproses.
+	Status        string `gorm:"type:varchar(16);index" json:"status"`
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+	ThumbnailKey  string `json:"-"`
+	ThumbnailSize int64  `json:"thumbnail_size"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
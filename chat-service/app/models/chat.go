@@ -6,14 +6,39 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	ContentText  = "text"
+	ContentImage = "image"
+	ContentFile  = "file"
+)
+
 type Message struct {
-	ID         int    `gorm:"primaryKey" json:"id"`
-	SenderID   int    `binding:"required"`
-	RecieverID int    `json:"reciever_id"`
-	GroupId    int    `json:"group_id"`
-	Content    string `gorm:"type:text" json:"content" binding:"required"`
-	CreatedAt  time.Time
-	DeletedAt  *gorm.DeletedAt
+	ID               int    `gorm:"primaryKey" json:"id"`
+	ConversationID   int    `gorm:"index" json:"conversation_id"`
+	SenderID         int    `binding:"required"`
+	RecieverID       int    `json:"reciever_id"`
+	GroupId          int    `json:"group_id"`
+	Content          string `gorm:"type:text" json:"content" binding:"required"`
+	ContentType      string `gorm:"type:varchar(16);not null;default:text" json:"content_type"`
+	ReplyToMessageID int    `gorm:"not null;default:0" json:"reply_to_message_id"`
+	ThreadRootID     int    `gorm:"index;not null;default:0" json:"thread_root_id"` // 0 jika bukan balasan di thread
+	ClientMessageID  string `gorm:"type:varchar(80);not null;default:''" json:"client_message_id"`
+	OriginMessageID  int    `gorm:"not null;default:0" json:"origin_message_id"` // pesan asli jika pesan ini diteruskan
+	OriginSenderID   int    `gorm:"not null;default:0" json:"origin_sender_id"`
+	CreatedAt        time.Time
+	EditedAt         *time.Time
+	ExpiresAt        *time.Time `gorm:"index"` // diisi dari Conversation.Retention, dihapus permanen oleh sweeper
+	DeletedAt        *gorm.DeletedAt
+	Attachments      []Attachment `gorm:"foreignKey:MessageID"`
+}
+
+// MessageEdit menyimpan isi pesan sebelum diedit
+type MessageEdit struct {
+	ID              int    `gorm:"primaryKey" json:"id"`
+	MessageID       int    `gorm:"index" json:"message_id"`
+	EditorID        int    `json:"editor_id"`
+	PreviousContent string `gorm:"type:text" json:"previous_content"`
+	EditedAt        time.Time
 }
 
 type MessageResponse struct {
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	ConversationDirect = "direct"
+	ConversationGroup  = "group"
+)
+
+type Conversation struct {
+	ID             int       `gorm:"primaryKey" json:"id"`
+	Type           string    `gorm:"not null" json:"type"`
+	DirectKey      *string   `gorm:"uniqueIndex" json:"-"`
+	GroupID        *int      `gorm:"uniqueIndex" json:"group_id"`
+	LastMessageID  int       `json:"last_message_id"`
+	LastActivityAt time.Time `gorm:"index"`
+	Retention      int       `gorm:"not null;default:0" json:"retention_seconds"` // detik; pesan baru kedaluwarsa setelah Retention, 0 berarti tidak pernah
+	CreatedAt      time.Time
+}
+
+type ConversationMember struct {
+	ID                int `gorm:"primaryKey" json:"id"`
+	ConversationID    int `gorm:"uniqueIndex:idx_conversation_member" json:"conversation_id"`
+	UserID            int `gorm:"uniqueIndex:idx_conversation_member;index" json:"user_id"`
+	LastReadMessageID int `json:"last_read_message_id"`
+	CreatedAt         time.Time
+}
+
+// DirectConversationKey menghasilkan key yang sama untuk pasangan user
+// yang sama, tidak peduli siapa pengirimnya
+func DirectConversationKey(userA, userB int) string {
+	if userA > userB {
+		userA, userB = userB, userA
+	}
+	return fmt.Sprintf("%d:%d", userA, userB)
+}
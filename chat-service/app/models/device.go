@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// DeviceCursor menyimpan id pesan terakhir yang sudah di-ack oleh device.
+// Saat device terhubung kembali, semua pesan setelah cursor dikirim ulang.
+type DeviceCursor struct {
+	ID                 int    `gorm:"primaryKey" json:"id"`
+	UserID             int    `gorm:"uniqueIndex:idx_device_cursor" json:"user_id"`
+	DeviceID           string `gorm:"uniqueIndex:idx_device_cursor" json:"device_id"`
+	LastAckedMessageID int    `json:"last_acked_message_id"`
+	UpdatedAt          time.Time
+}
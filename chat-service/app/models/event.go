@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// UserEvent adalah log event milik user yang diurutkan dengan Seq,
+// dipakai oleh Sync untuk mengirim ulang event setelah client terputus
+type UserEvent struct {
+	ID        int    `gorm:"primaryKey" json:"id"`
+	UserID    int    `gorm:"uniqueIndex:idx_user_event_seq" json:"user_id"`
+	Seq       int64  `gorm:"uniqueIndex:idx_user_event_seq" json:"seq"`
+	Type      string `json:"type"`
+	MessageID int    `gorm:"index" json:"message_id"`
+	Payload   []byte `json:"-"`
+	CreatedAt time.Time
+}
+
+// UserSequence menyimpan seq terakhir milik user. PrunedSeq adalah seq
+// terbesar yang sudah dihapus karena melewati masa simpan event.
+type UserSequence struct {
+	UserID    int   `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	LastSeq   int64 `json:"last_seq"`
+	PrunedSeq int64 `gorm:"not null;default:0" json:"pruned_seq"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	GroupRoleAdmin  = "admin"
+	GroupRoleMember = "member"
+)
+
+type Group struct {
+	ID        int    `gorm:"primaryKey" json:"id"`
+	Name      string `gorm:"not null" json:"name" binding:"required"`
+	OwnerID   int    `json:"owner_id"`
+	Members   []GroupMember
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+type GroupMember struct {
+	ID        int    `gorm:"primaryKey" json:"id"`
+	GroupID   int    `gorm:"uniqueIndex:idx_group_member" json:"group_id"`
+	UserID    int    `gorm:"uniqueIndex:idx_group_member;index" json:"user_id"`
+	Role      string `gorm:"default:member" json:"role"`
+	CreatedAt time.Time
+}
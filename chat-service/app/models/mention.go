@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Mention mencatat user yang disebut dengan @ di sebuah pesan
+type Mention struct {
+	ID             int `gorm:"primaryKey" json:"id"`
+	MessageID      int `gorm:"uniqueIndex:idx_mentions_message_user" json:"message_id"`
+	UserID         int `gorm:"uniqueIndex:idx_mentions_message_user;index" json:"user_id"`
+	ConversationID int `gorm:"index" json:"conversation_id"`
+	SenderID       int `json:"sender_id"`
+	CreatedAt      time.Time
+}
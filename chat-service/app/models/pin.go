@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// PinnedMessage menandai pesan yang disematkan di percakapan
+type PinnedMessage struct {
+	ID             int `gorm:"primaryKey" json:"id"`
+	ConversationID int `gorm:"index" json:"conversation_id"`
+	MessageID      int `gorm:"uniqueIndex" json:"message_id"`
+	PinnedBy       int `json:"pinned_by"`
+	CreatedAt      time.Time
+}
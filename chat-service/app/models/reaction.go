@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// MessageReaction menyimpan emoji yang diberikan user pada sebuah pesan
+type MessageReaction struct {
+	ID        int    `gorm:"primaryKey" json:"id"`
+	MessageID int    `gorm:"uniqueIndex:idx_reaction_message_user_emoji" json:"message_id"`
+	UserID    int    `gorm:"uniqueIndex:idx_reaction_message_user_emoji" json:"user_id"`
+	Emoji     string `gorm:"type:varchar(32);uniqueIndex:idx_reaction_message_user_emoji" json:"emoji"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+const (
+	ReceiptSent      = "sent"
+	ReceiptDelivered = "delivered"
+	ReceiptRead      = "read"
+)
+
+// MessageReceipt menyimpan status pengiriman pesan untuk setiap penerima
+type MessageReceipt struct {
+	ID          int    `gorm:"primaryKey" json:"id"`
+	MessageID   int    `gorm:"uniqueIndex:idx_receipt_message_user" json:"message_id"`
+	UserID      int    `gorm:"uniqueIndex:idx_receipt_message_user;index" json:"user_id"`
+	Status      string `gorm:"default:sent" json:"status"`
+	DeliveredAt *time.Time
+	ReadAt      *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	ScheduledPending   = "pending"
+	ScheduledSending   = "sending"
+	ScheduledSent      = "sent"
+	ScheduledCancelled = "cancelled"
+	ScheduledFailed    = "failed"
+)
+
+// ScheduledMessage adalah pesan yang dikirim scheduler pada SendAt.
+// Request berisi SendMessageRequest yang di-encode protobuf.
+type ScheduledMessage struct {
+	ID        int       `gorm:"primaryKey" json:"id"`
+	SenderID  int       `gorm:"index" json:"sender_id"`
+	Request   []byte    `json:"-"`
+	SendAt    time.Time `gorm:"index" json:"send_at"`
+	Status    string    `gorm:"type:varchar(16);index" json:"status"`
+	Error     string    `json:"error"`
+	SentAt    *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
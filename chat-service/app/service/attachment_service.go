@@ -0,0 +1,395 @@
+package service
+
+import (
+	"bytes"
+	"chat-service/app/media"
+	"chat-service/app/models"
+	"chat-service/app/storage"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// attachmentRoute adalah path download lampiran di api-gateway
+	attachmentRoute = "/chat/attachments/"
+
+	attachmentChunkSize      = 32 * 1024
+	maxAttachmentsPerMessage = 10
+)
+
+func (cs *ChatServiceServer) UploadAttachment(stream pb.ChatService_UploadAttachmentServer) error {
+
+	ctx := stream.Context()
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return fmt.Errorf("failed parsing id %s", err)
+	}
+
+	req, err := stream.Recv()
+	if err != nil {
+		return fmt.Errorf("failed to receive attachment info: %v", err)
+	}
+
+	info := req.GetInfo()
+	if info == nil {
+		return fmt.Errorf("first frame must contain attachment info")
+	}
+
+	name := path.Base(strings.TrimSpace(strings.ReplaceAll(info.Name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return fmt.Errorf("attachment name is required")
+	}
+
+	mimeType, _, err := mime.ParseMediaType(info.MimeType)
+	if err != nil || !cs.allowedType(mimeType) {
+		return fmt.Errorf("attachment type %q is not allowed", info.MimeType)
+	}
+
+	maxSize := int64(cs.cfg.Storage.MaxSizeMB) << 20
+	if info.Size <= 0 {
+		return fmt.Errorf("attachment size is required")
+	}
+	if info.Size > maxSize {
+		return fmt.Errorf("attachment exceeds the %d MB limit", cs.cfg.Storage.MaxSizeMB)
+	}
+
+	// MIME type dari client tidak dipercaya begitu saja: isi file diperiksa
+	// seperti di api-gateway karena client gRPC lain bisa melewati gateway
+	upload := io.LimitReader(&uploadReader{stream: stream}, info.Size+1)
+	head := make([]byte, 512)
+	n, err := io.ReadFull(upload, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("failed to receive attachment: %v", err)
+	}
+	mimeType, err = sniffMimeType(head[:n], mimeType)
+	if err != nil {
+		return err
+	}
+	if !cs.allowedType(mimeType) {
+		return fmt.Errorf("attachment type %q is not allowed", mimeType)
+	}
+	// Gambar yang lokasinya tidak bisa dihapus worker tidak diterima
+	if strings.HasPrefix(mimeType, "image/") && !processableImages[mimeType] {
+		return fmt.Errorf("image type %q is not supported", mimeType)
+	}
+
+	key := fmt.Sprintf("attachments/%d/%s%s", *userID, helper.NewSessionID(), helper.NewSessionID())
+	hash := sha256.New()
+	body := &countReader{r: io.TeeReader(io.MultiReader(bytes.NewReader(head[:n]), upload), hash)}
+
+	if err := cs.store.Put(ctx, key, body, info.Size, mimeType); err != nil {
+		cs.deleteBlob(key)
+		return fmt.Errorf("failed to store attachment: %v", err)
+	}
+
+	if body.n != info.Size {
+		cs.deleteBlob(key)
+		return fmt.Errorf("attachment size does not match: expected %d bytes, got %d", info.Size, body.n)
+	}
+
+	attachment := models.Attachment{
+		UploaderID: *userID,
+		Name:       name,
+		MimeType:   mimeType,
+		Size:       body.n,
+		Checksum:   hex.EncodeToString(hash.Sum(nil)),
+		StorageKey: key,
+	}
+	if processableImages[mimeType] {
+		attachment.Status = models.AttachmentPending
+	}
+	if err := cs.db.Create(&attachment).Error; err != nil {
+		cs.deleteBlob(key)
+		return fmt.Errorf("failed to save attachment: %v", err)
+	}
+
+	if attachment.Status == models.AttachmentPending {
+		cs.wakeMediaWorker()
+	}
+
+	return stream.SendAndClose(attachmentProto(attachment))
+}
+
+func (cs *ChatServiceServer) DownloadAttachment(req *pb.DownloadAttachmentRequest, stream pb.ChatService_DownloadAttachmentServer) error {
+
+	ctx := stream.Context()
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return fmt.Errorf("failed parsing id %s", err)
+	}
+
+	var attachment models.Attachment
+	err = cs.db.First(&attachment, req.AttachmentId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("attachment not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to get attachment: %v", err)
+	}
+
+	if err := cs.checkAttachmentAccess(attachment, *userID); err != nil {
+		return err
+	}
+
+	info := attachmentProto(attachment)
+	key := attachment.StorageKey
+	if req.Thumbnail {
+		if attachment.ThumbnailKey == "" {
+			return fmt.Errorf("attachment has no thumbnail")
+		}
+		key = attachment.ThumbnailKey
+		info.Name = strings.TrimSuffix(attachment.Name, path.Ext(attachment.Name)) + "_thumb.jpg"
+		info.MimeType = media.ThumbnailMimeType
+		info.Size = attachment.ThumbnailSize
+		info.Checksum = ""
+	} else if err := downloadable(attachment, *userID); err != nil {
+		return err
+	}
+
+	blob, err := cs.store.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("attachment file not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to read attachment: %v", err)
+	}
+	defer blob.Close()
+
+	if err := stream.Send(&pb.DownloadAttachmentResponse{Data: &pb.DownloadAttachmentResponse_Info{Info: info}}); err != nil {
+		return err
+	}
+
+	buf := make([]byte, attachmentChunkSize)
+	for {
+		n, err := blob.Read(buf)
+		if n > 0 {
+			chunk := &pb.DownloadAttachmentResponse{Data: &pb.DownloadAttachmentResponse_Chunk{Chunk: buf[:n]}}
+			if err := stream.Send(chunk); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read attachment: %v", err)
+		}
+	}
+}
+
+// checkAttachmentAccess memastikan user adalah pengunggah lampiran atau
+// peserta percakapan tempat lampiran dikirim
+func (cs *ChatServiceServer) checkAttachmentAccess(attachment models.Attachment, userID int) error {
+	if attachment.MessageID == 0 {
+		if attachment.UploaderID == userID {
+			return nil
+		}
+		return fmt.Errorf("attachment not found")
+	}
+
+	// Lampiran ikut tidak bisa diunduh setelah pesannya kedaluwarsa
+	var message models.Message
+	err := cs.db.Scopes(notExpired).First(&message, attachment.MessageID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("attachment not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to get message: %v", err)
+	}
+
+	if attachment.UploaderID == userID {
+		return nil
+	}
+	return cs.checkConversationMember(message.ConversationID, userID)
+}
+
+// downloadable memastikan gambar yang belum (atau gagal) dibersihkan dari
+// lokasi EXIF hanya bisa diunduh oleh pengunggahnya
+func downloadable(attachment models.Attachment, userID int) error {
+	if attachment.UploaderID == userID {
+		return nil
+	}
+	switch attachment.Status {
+	case models.AttachmentPending, models.AttachmentProcessing:
+		return fmt.Errorf("attachment is still processing")
+	case models.AttachmentFailed:
+		return fmt.Errorf("attachment could not be processed")
+	}
+	// Gambar lama yang diunggah sebelum jenisnya dibatasi tidak pernah dibersihkan
+	if strings.HasPrefix(attachment.MimeType, "image/") && !processableImages[attachment.MimeType] {
+		return fmt.Errorf("attachment could not be processed")
+	}
+	return nil
+}
+
+// sniffMimeType menentukan MIME type dari isi file. Type dari client hanya
+// dipakai jika isinya tidak dikenali, dan tidak boleh berupa gambar karena
+// gambar selalu bisa dikenali dari isinya.
+func sniffMimeType(head []byte, declared string) (string, error) {
+	detected, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "", fmt.Errorf("failed to detect attachment type: %v", err)
+	}
+
+	if detected != "application/octet-stream" {
+		return detected, nil
+	}
+	if strings.HasPrefix(declared, "image/") {
+		return "", fmt.Errorf("attachment content is not a valid %s", declared)
+	}
+	return declared, nil
+}
+
+// allowedType mencocokkan MIME type dengan daftar di konfigurasi,
+// termasuk wildcard seperti "image/*"
+func (cs *ChatServiceServer) allowedType(mimeType string) bool {
+	for _, allowed := range cs.cfg.Storage.AllowedTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == mimeType {
+			return true
+		}
+		if strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cs *ChatServiceServer) deleteBlob(key string) {
+	if err := cs.store.Delete(context.Background(), key); err != nil {
+		log.Printf("Error deleting blob %s: %v", key, err)
+	}
+}
+
+// linkAttachments menautkan lampiran milik pengirim ke pesan yang baru dibuat.
+// Lampiran yang sudah dipakai pesan lain (misalnya pesan ke beberapa penerima)
+// disalin metadatanya dan tetap menunjuk ke blob yang sama.
+func linkAttachments(tx *gorm.DB, message *models.Message, ids []int32) error {
+	attachmentIDs := uniqueIDs(ids, 0)
+	if len(attachmentIDs) == 0 {
+		return nil
+	}
+	if len(attachmentIDs) > maxAttachmentsPerMessage {
+		return fmt.Errorf("a message can have at most %d attachments", maxAttachmentsPerMessage)
+	}
+
+	var attachments []models.Attachment
+	err := tx.Where("id IN ? AND uploader_id = ?", attachmentIDs, message.SenderID).Order("id ASC").Find(&attachments).Error
+	if err != nil {
+		return fmt.Errorf("failed to get attachments: %v", err)
+	}
+	if len(attachments) != len(attachmentIDs) {
+		return fmt.Errorf("attachment not found")
+	}
+
+	for i := range attachments {
+		if attachments[i].MessageID == 0 {
+			result := tx.Model(&models.Attachment{}).
+				Where("id = ? AND message_id = 0", attachments[i].ID).
+				Update("message_id", message.ID)
+			if result.Error != nil {
+				return fmt.Errorf("failed to link attachment: %v", result.Error)
+			}
+			if result.RowsAffected == 1 {
+				attachments[i].MessageID = message.ID
+				continue
+			}
+		}
+
+		clone := attachments[i]
+		clone.ID = 0
+		clone.MessageID = message.ID
+		clone.CreatedAt = time.Now()
+		if err := tx.Create(&clone).Error; err != nil {
+			return fmt.Errorf("failed to link attachment: %v", err)
+		}
+		attachments[i] = clone
+	}
+
+	message.Attachments = attachments
+
+	// Pesan yang semua lampirannya gambar ditampilkan sebagai pesan gambar
+	contentType := models.ContentImage
+	for _, attachment := range attachments {
+		if !strings.HasPrefix(attachment.MimeType, "image/") {
+			contentType = models.ContentFile
+		}
+	}
+	message.ContentType = contentType
+
+	return tx.Model(&models.Message{}).Where("id = ?", message.ID).Update("content_type", contentType).Error
+}
+
+func attachmentProto(attachment models.Attachment) *pb.Attachment {
+	a := &pb.Attachment{
+		Id:       int32(attachment.ID),
+		Name:     attachment.Name,
+		MimeType: attachment.MimeType,
+		Size:     attachment.Size,
+		Checksum: attachment.Checksum,
+		Url:      fmt.Sprintf("%s%d", attachmentRoute, attachment.ID),
+		Width:    int32(attachment.Width),
+		Height:   int32(attachment.Height),
+		Status:   attachment.Status,
+	}
+	if attachment.ThumbnailKey != "" {
+		a.ThumbnailUrl = fmt.Sprintf("%s%d/thumbnail", attachmentRoute, attachment.ID)
+	}
+	return a
+}
+
+func attachmentProtos(attachments []models.Attachment) []*pb.Attachment {
+	result := make([]*pb.Attachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		result = append(result, attachmentProto(attachment))
+	}
+	return result
+}
+
+// uploadReader membaca potongan file dari stream UploadAttachment
+type uploadReader struct {
+	stream pb.ChatService_UploadAttachmentServer
+	buf    []byte
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+
+		chunk, ok := req.Data.(*pb.UploadAttachmentRequest_Chunk)
+		if !ok {
+			return 0, fmt.Errorf("expected attachment chunk")
+		}
+		r.buf = chunk.Chunk
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -1,37 +1,51 @@
 package service
 
 import (
+	"chat-service/app/bus"
 	"chat-service/app/models"
+	"chat-service/app/storage"
+	"chat-service/config"
 	"chat-service/helper"
 	pb "chat-service/proto/script"
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"gorm.io/gorm"
 )
 
-var rdb = redis.NewClient(&redis.Options{
-	Addr: "localhost:6379",
-})
-
 type ChatServiceServer struct {
 	pb.UnimplementedChatServiceServer
 	db           *gorm.DB
-	Clients      map[int]chan *pb.StreamMessagesResponse // Menyimpan channel untuk streaming pesan
+	rdb          *redis.Client
+	bus          bus.Bus
+	store        storage.BlobStore
+	cfg          config.Config
+	Clients      map[int]map[string]*subscriber // Menyimpan stream setiap device milik user
 	ClientsMutex *sync.Mutex
+	busSubs      map[int]*busSubscription // Subscription bus per user di replica ini
+	mediaWake    chan struct{}
+	typing       *typingTracker
 }
 
-func NewChatServer(db *gorm.DB) *ChatServiceServer {
+func NewChatServer(db *gorm.DB, rdb *redis.Client, eventBus bus.Bus, store storage.BlobStore, cfg config.Config) *ChatServiceServer {
 	return &ChatServiceServer{
 		db:           db,
-		Clients:      make(map[int]chan *pb.StreamMessagesResponse),
+		rdb:          rdb,
+		bus:          eventBus,
+		store:        store,
+		cfg:          cfg,
+		Clients:      make(map[int]map[string]*subscriber),
 		ClientsMutex: &sync.Mutex{},
+		busSubs:      make(map[int]*busSubscription),
+		mediaWake:    make(chan struct{}, 1),
+		typing:       newTypingTracker(),
 	}
 }
 
@@ -42,58 +56,169 @@ func (cs *ChatServiceServer) SendMessage(ctx context.Context, req *pb.SendMessag
 		return nil, fmt.Errorf("failed parsing id %s", err)
 	}
 
-	var receiverIDs []int32
+	if err := checkClientMessageID(req.ClientMessageId); err != nil {
+		return nil, err
+	}
+
+	return cs.sendMessage(ctx, *senderId, req)
+}
+
+// sendMessage menyimpan pesan atas nama senderID lalu mengirimnya ke stream
+// penerima. Dipakai SendMessage dan scheduler pesan terjadwal.
+func (cs *ChatServiceServer) sendMessage(ctx context.Context, senderID int, req *pb.SendMessageRequest) (*pb.SendMessageResponse, error) {
+	var err error
+	req.ClientMessageId, err = normalizeClientMessageID(req.ClientMessageId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Kiriman ulang dengan client_message_id yang sama mengembalikan pesan lama
+	if res, err := cs.duplicateSend(senderID, req.ClientMessageId); res != nil || err != nil {
+		return res, err
+	}
+
+	if req.GroupId != 0 {
+		return cs.sendGroupMessage(ctx, senderID, req)
+	}
 
-	if len(req.ReceiverId) == 0 {
+	// Penerima yang sama cukup satu pesan, agar client_message_id per penerima tetap unik
+	receiverIDs := uniqueIDs(req.ReceiverId, 0)
+	if len(receiverIDs) == 0 {
 		return nil, fmt.Errorf("receiver ID is required")
-	} else if len(req.ReceiverId) == 1 {
-		receiverIDs = []int32{req.ReceiverId[0]}
-	} else {
-		receiverIDs = req.ReceiverId
 	}
 
 	var messages []models.Message
+	var mentions []models.Mention
 
-	for _, receiverId := range receiverIDs {
+	err = cs.db.Transaction(func(tx *gorm.DB) error {
+		for i, receiverID := range receiverIDs {
 
-		message := models.Message{
-			SenderID:   *senderId,
-			RecieverID: int(receiverId),
-			Content:    req.Content,
-			CreatedAt:  time.Now(),
-		}
-		messages = append(messages, message)
-
-		cs.ClientsMutex.Lock()
-		if ch, ok := cs.Clients[int(receiverId)]; ok {
-			cs.ClientsMutex.Unlock()
-			ch <- &pb.StreamMessagesResponse{
-				SenderId:  int32(*senderId),
-				Content:   req.Content,
-				Timestamp: time.Now().Format(time.RFC3339),
+			conversation, err := directConversation(tx, senderID, receiverID)
+			if err != nil {
+				return err
+			}
+
+			message := models.Message{
+				ConversationID:  conversation.ID,
+				SenderID:        senderID,
+				RecieverID:      receiverID,
+				Content:         req.Content,
+				ContentType:     models.ContentText,
+				ClientMessageID: clientMessageKey(req.ClientMessageId, i, receiverID),
+				CreatedAt:       time.Now(),
+			}
+
+			applyRetention(&message, conversation)
+			if err := applyReply(tx, &message, req); err != nil {
+				return err
+			}
+
+			if err := tx.Create(&message).Error; err != nil {
+				return fmt.Errorf("failed to save messages to database: %v", err)
+			}
+
+			if err := linkAttachments(tx, &message, req.AttachmentIds); err != nil {
+				return err
+			}
+
+			if err := touchConversation(tx, message); err != nil {
+				return err
+			}
+
+			if err := createReceipts(tx, message.ID, []int{message.RecieverID}); err != nil {
+				return err
 			}
-		} else {
-			cs.ClientsMutex.Unlock()
-
-			key := fmt.Sprintf("user:%d:offline_messages", receiverId)
-			messageJSON, _ := json.Marshal(&pb.StreamMessagesResponse{
-				SenderId:  int32(*senderId),
-				Content:   req.Content,
-				Timestamp: time.Now().Format(time.RFC3339),
-			})
-			err := rdb.RPush(ctx, key, messageJSON).Err()
+
+			mentioned, err := recordMentions(tx, message)
 			if err != nil {
-				log.Printf("Error storing message in Redis list: %v", err)
+				return err
 			}
+
+			messages = append(messages, message)
+			mentions = append(mentions, mentioned...)
+		}
+		return nil
+	})
+	if err != nil {
+		// Kiriman yang sama bisa berjalan bersamaan dan ditolak unique index
+		if res, dupErr := cs.duplicateSend(senderID, req.ClientMessageId); res != nil && dupErr == nil {
+			return res, nil
 		}
+		return nil, err
 	}
 
-	err = cs.db.Create(&messages).Error
-	if err != nil {
-		return nil, fmt.Errorf("failed to save messages to database: %v", err)
+	for _, message := range messages {
+		cs.deliver(ctx, message.RecieverID, messageEvent(message))
+		cs.notifyMentions(ctx, message, mentions)
 	}
 
-	return &pb.SendMessageResponse{Status: "Sent Messages Successfully"}, nil
+	return sendResponse(messages, false), nil
+}
+
+func messageEvent(message models.Message) *pb.StreamMessagesResponse {
+	return &pb.StreamMessagesResponse{
+		Type:             EventMessage,
+		MessageId:        int32(message.ID),
+		ConversationId:   int32(message.ConversationID),
+		SenderId:         int32(message.SenderID),
+		GroupId:          int32(message.GroupId),
+		Content:          message.Content,
+		Timestamp:        message.CreatedAt.Format(time.RFC3339),
+		ReplyToMessageId: int32(message.ReplyToMessageID),
+		ThreadRootId:     int32(message.ThreadRootID),
+		Attachments:      attachmentProtos(message.Attachments),
+		ContentType:      message.ContentType,
+		ExpiresAt:        formatOptionalTime(message.ExpiresAt),
+
+		ForwardedFromMessageId: int32(message.OriginMessageID),
+		ForwardedFromSenderId:  int32(message.OriginSenderID),
+	}
+}
+
+// deliver mengirim event ke stream satu user; lihat deliverAll
+func (cs *ChatServiceServer) deliver(ctx context.Context, userID int, msg *pb.StreamMessagesResponse) {
+	cs.deliverAll(ctx, []int{userID}, msg)
+}
+
+// deliverAll mengirim event yang sama ke stream beberapa user di replica
+// manapun. Pesan untuk user yang sedang offline tidak hilang karena akan
+// diputar ulang dari Postgres saat device-nya terhubung kembali (lihat
+// replayOffline). Event yang tersimpan dicatat di log setiap user dengan
+// seq untuk Sync dalam satu statement. Publish tidak menunggu stream yang
+// lambat; lihat subscriber.send.
+func (cs *ChatServiceServer) deliverAll(ctx context.Context, userIDs []int, msg *pb.StreamMessagesResponse) {
+	ids := make([]int, 0, len(userIDs))
+	seen := make(map[int]bool, len(userIDs))
+	for _, id := range userIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	sort.Ints(ids)
+
+	var seqs map[int]int64
+	if durableEvents[msg.Type] {
+		var err error
+		if seqs, err = cs.recordEvents(ids, msg); err != nil {
+			log.Printf("Error recording event for users %v: %v", ids, err)
+		}
+	}
+
+	for _, userID := range ids {
+		event := msg
+		if seq, ok := seqs[userID]; ok {
+			event = proto.Clone(msg).(*pb.StreamMessagesResponse)
+			event.Seq = seq
+		}
+
+		if _, err := cs.bus.Publish(ctx, userID, event); err != nil {
+			log.Printf("Error publishing event to user %d: %v", userID, err)
+		}
+	}
 }
 
 func (s *ChatServiceServer) StreamMessages(req *pb.StreamMessagesRequest, stream pb.ChatService_StreamMessagesServer) error {
@@ -105,41 +230,55 @@ func (s *ChatServiceServer) StreamMessages(req *pb.StreamMessagesRequest, stream
 		return fmt.Errorf("failed parsing id %s", err)
 	}
 
-	clientChan := make(chan *pb.StreamMessagesResponse, 10)
-	s.ClientsMutex.Lock()
-	s.Clients[*senderId] = clientChan
-	s.ClientsMutex.Unlock()
+	sub, err := s.subscribe(ctx, *senderId, helper.DeviceID(ctx))
+	if err != nil {
+		return err
+	}
+	defer s.unsubscribe(sub)
 
-	go func() {
-		key := fmt.Sprintf("user:%d:offline_messages:", *senderId)
-		messages, err := rdb.LRange(context.Background(), key, 0, -1).Result()
-		if err != nil {
-			log.Printf("Error reading offline messages for user %d: %v", *senderId, err)
-			return
+	// StreamMessages tidak punya frame ack, sehingga pesan dianggap
+	// sudah di-ack setelah berhasil dikirim ke stream
+	push := func(msg *pb.StreamMessagesResponse) error {
+		if err := stream.Send(msg); err != nil {
+			log.Printf("Error sending message to client: %v", err)
+			return err
 		}
+		sub.markPushed(msg)
 
-		for _, msg := range messages {
-			var message pb.StreamMessagesResponse
-			if err := json.Unmarshal([]byte(msg), &message); err == nil {
-				clientChan <- &message
-			}
+		if msg.Type == EventMessage && msg.MessageId != 0 {
+			s.markDelivered(*senderId, msg)
+			s.advanceCursor(sub, int(msg.MessageId))
 		}
+		return nil
+	}
 
-		rdb.Del(context.Background(), key)
-	}()
+	if err := s.replayOffline(sub, push); err != nil {
+		return err
+	}
 
-	for msg := range clientChan {
-		if err := stream.Send(msg); err != nil {
-			log.Printf("Error sending message to client: %v", err)
-			break
+	for {
+		select {
+		case msg := <-sub.ch:
+			if sub.alreadyReplayed(msg) {
+				continue
+			}
+			if err := push(msg); err != nil {
+				return err
+			}
+		case msg := <-sub.urgent:
+			if err := push(msg); err != nil {
+				return err
+			}
+		case <-sub.lag:
+			if err := s.catchUp(sub, push); err != nil {
+				return err
+			}
+		case <-sub.done:
+			return nil
+		case <-ctx.Done():
+			return nil
 		}
 	}
-
-	s.ClientsMutex.Lock()
-	delete(s.Clients, *senderId)
-	s.ClientsMutex.Unlock()
-
-	return nil
 }
 
 func (s *ChatServiceServer) ListMessage(ctx context.Context, req *emptypb.Empty) (*pb.ListMessageResponse, error) {
@@ -154,7 +293,8 @@ func (s *ChatServiceServer) ListMessage(ctx context.Context, req *emptypb.Empty)
 	err = s.db.Table("messages AS m").
 		Select("DISTINCT ON (m.sender_id) u.first_name as sender, m.content as message").
 		Joins("JOIN users AS u ON m.sender_id = u.id").
-		Where("m.reciever_id = ?", userID).
+		Where("m.reciever_id = ? AND m.deleted_at IS NULL", userID).
+		Where("(m.expires_at IS NULL OR m.expires_at > NOW())").
 		Order("m.sender_id, m.created_at DESC").
 		Scan(&list).Error
 
@@ -185,7 +325,8 @@ func (s *ChatServiceServer) ListMessageBySender(ctx context.Context, req *pb.Lis
 	var messages []string
 	err = s.db.Table("messages").
 		Select("content").
-		Where("sender_id = ? AND reciever_id = ?", req.SenderId, userID).
+		Where("sender_id = ? AND reciever_id = ? AND deleted_at IS NULL", req.SenderId, userID).
+		Where("(expires_at IS NULL OR expires_at > NOW())").
 		Order("created_at ASC").
 		Pluck("content", &messages).Error
 	if err != nil {
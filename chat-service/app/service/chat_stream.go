@@ -0,0 +1,273 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	EventPresence = "presence"
+	EventTyping   = "typing"
+)
+
+// Chat adalah stream dua arah: client mengirim ClientFrame (send, typing,
+// ack, read, sync, thread) dan menerima ServerFrame (message, receipt,
+// presence, error, ack, sync, thread)
+func (cs *ChatServiceServer) Chat(stream pb.ChatService_ChatServer) error {
+
+	ctx := stream.Context()
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return fmt.Errorf("failed parsing id %s", err)
+	}
+
+	sub, err := cs.subscribe(ctx, *userID, helper.DeviceID(ctx))
+	if err != nil {
+		return err
+	}
+	defer cs.unsubscribe(sub)
+
+	push := func(msg *pb.StreamMessagesResponse) error {
+		if err := stream.Send(serverFrame(msg)); err != nil {
+			log.Printf("Error sending frame to user %d: %v", *userID, err)
+			return err
+		}
+		sub.markPushed(msg)
+
+		if msg.Type == EventMessage && msg.MessageId != 0 {
+			cs.markDelivered(*userID, msg)
+		}
+		return nil
+	}
+
+	if err := cs.replayOffline(sub, push); err != nil {
+		return err
+	}
+
+	replies := make(chan *pb.ServerFrame, 10)
+	recvErr := make(chan error, 1)
+
+	go func() {
+		for {
+			frame, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+
+			select {
+			case replies <- cs.handleClientFrame(ctx, sub, frame):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case msg := <-sub.ch:
+			if sub.alreadyReplayed(msg) {
+				continue
+			}
+			if err := push(msg); err != nil {
+				return err
+			}
+		case msg := <-sub.urgent:
+			if err := push(msg); err != nil {
+				return err
+			}
+		case reply := <-replies:
+			if err := stream.Send(reply); err != nil {
+				log.Printf("Error sending frame to user %d: %v", *userID, err)
+				return err
+			}
+		case err := <-recvErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case <-sub.lag:
+			if err := cs.catchUp(sub, push); err != nil {
+				return err
+			}
+		case <-sub.done:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (cs *ChatServiceServer) handleClientFrame(ctx context.Context, sub *subscriber, frame *pb.ClientFrame) *pb.ServerFrame {
+	userID := sub.userID
+	var status string
+	var err error
+
+	switch f := frame.Frame.(type) {
+	case *pb.ClientFrame_Send:
+		var res *pb.SendMessageResponse
+		res, err = cs.SendMessage(ctx, f.Send)
+		if err == nil {
+			return &pb.ServerFrame{Id: frame.Id, Frame: &pb.ServerFrame_Ack{Ack: &pb.Ack{
+				Status:    res.Status,
+				Messages:  res.Messages,
+				Duplicate: res.Duplicate,
+			}}}
+		}
+	case *pb.ClientFrame_Typing:
+		err = cs.sendTyping(userID, f.Typing)
+		status = "Typing sent"
+	case *pb.ClientFrame_Ack:
+		err = cs.ackMessage(sub, int(f.Ack.MessageId))
+		status = "Message acknowledged"
+	case *pb.ClientFrame_Read:
+		var res *pb.MarkReadResponse
+		res, err = cs.MarkRead(ctx, f.Read)
+		if err == nil {
+			status = res.Status
+		}
+	case *pb.ClientFrame_Sync:
+		var res *pb.SyncResponse
+		res, err = cs.Sync(ctx, f.Sync)
+		if err == nil {
+			return &pb.ServerFrame{Id: frame.Id, Frame: &pb.ServerFrame_Sync{Sync: res}}
+		}
+	case *pb.ClientFrame_Thread:
+		var res *pb.ListThreadResponse
+		res, err = cs.ListThread(ctx, f.Thread)
+		if err == nil {
+			return &pb.ServerFrame{Id: frame.Id, Frame: &pb.ServerFrame_Thread{Thread: res}}
+		}
+	default:
+		err = fmt.Errorf("unknown frame")
+	}
+
+	if err != nil {
+		return &pb.ServerFrame{Id: frame.Id, Frame: &pb.ServerFrame_Error{Error: &pb.ErrorFrame{Message: err.Error()}}}
+	}
+
+	return &pb.ServerFrame{Id: frame.Id, Frame: &pb.ServerFrame_Ack{Ack: &pb.Ack{Status: status}}}
+}
+
+func serverFrame(msg *pb.StreamMessagesResponse) *pb.ServerFrame {
+	switch {
+	case msg.Type == EventReceipt && msg.Receipt != nil:
+		return &pb.ServerFrame{Frame: &pb.ServerFrame_Receipt{Receipt: msg.Receipt}}
+	case msg.Type == EventPresence && msg.Presence != nil:
+		return &pb.ServerFrame{Frame: &pb.ServerFrame_Presence{Presence: msg.Presence}}
+	default:
+		return &pb.ServerFrame{Frame: &pb.ServerFrame_Message{Message: msg}}
+	}
+}
+
+func (cs *ChatServiceServer) broadcastPresence(userID int, online bool) {
+	peerIDs, err := cs.conversationPeerIDs(userID)
+	if err != nil {
+		log.Printf("Error getting peers of user %d: %v", userID, err)
+		return
+	}
+
+	event := &pb.StreamMessagesResponse{
+		Type:      EventPresence,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Presence: &pb.Presence{
+			UserId:    int32(userID),
+			Online:    online,
+			Timestamp: time.Now().Format(time.RFC3339),
+		},
+	}
+
+	cs.deliverAll(context.Background(), peerIDs, event)
+}
+
+// conversationPeerIDs mengambil semua user yang berada dalam percakapan yang sama
+func (cs *ChatServiceServer) conversationPeerIDs(userID int) ([]int, error) {
+	var peerIDs []int
+	err := cs.db.Table("conversation_members AS peer").
+		Distinct("peer.user_id").
+		Joins("JOIN conversation_members AS me ON me.conversation_id = peer.conversation_id").
+		Where("me.user_id = ? AND peer.user_id <> ?", userID, userID).
+		Pluck("peer.user_id", &peerIDs).Error
+	return peerIDs, err
+}
+
+// sendTyping meneruskan status mengetik ke peserta lain. Typing true
+// di-throttle dan otomatis berakhir jika tidak diperbarui (lihat typingTracker).
+func (cs *ChatServiceServer) sendTyping(userID int, frame *pb.TypingFrame) error {
+	conversation, err := cs.findConversation(userID, frame.ConversationId, frame.PeerId, frame.GroupId)
+	if err != nil {
+		return err
+	}
+	if conversation == nil {
+		return fmt.Errorf("conversation not found")
+	}
+
+	var memberIDs []int
+	err = cs.db.Model(&models.ConversationMember{}).
+		Where("conversation_id = ? AND user_id <> ?", conversation.ID, userID).
+		Pluck("user_id", &memberIDs).Error
+	if err != nil {
+		return fmt.Errorf("failed to get conversation members: %v", err)
+	}
+
+	key := typingKey{userID: userID, conversationID: conversation.ID}
+	if frame.Typing {
+		stop := func() { cs.broadcastTyping(userID, conversation.ID, memberIDs, false) }
+		if !cs.typing.start(key, stop) {
+			return nil
+		}
+	} else if !cs.typing.stop(key) {
+		return nil
+	}
+
+	cs.broadcastTyping(userID, conversation.ID, memberIDs, frame.Typing)
+	return nil
+}
+
+func (cs *ChatServiceServer) broadcastTyping(userID, conversationID int, memberIDs []int, typing bool) {
+	event := &pb.StreamMessagesResponse{
+		Type:           EventTyping,
+		SenderId:       int32(userID),
+		ConversationId: int32(conversationID),
+		Timestamp:      time.Now().Format(time.RFC3339),
+		Typing: &pb.Typing{
+			ConversationId: int32(conversationID),
+			UserId:         int32(userID),
+			Typing:         typing,
+		},
+	}
+	if typing {
+		event.Typing.ExpiresInSeconds = int32(typingTimeout / time.Second)
+	}
+
+	cs.deliverAll(context.Background(), memberIDs, event)
+}
+
+// ackMessage dipanggil saat client mengonfirmasi pesan sudah diterima
+func (cs *ChatServiceServer) ackMessage(sub *subscriber, messageID int) error {
+	var message models.Message
+	err := cs.db.Scopes(notExpired).First(&message, messageID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("message not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to get message: %v", err)
+	}
+
+	if err := cs.checkConversationMember(message.ConversationID, sub.userID); err != nil {
+		return err
+	}
+
+	cs.markDelivered(sub.userID, messageEvent(message))
+	cs.advanceCursor(sub, message.ID)
+	return nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"chat-service/app/models"
+	pb "chat-service/proto/script"
+	"fmt"
+	"strings"
+)
+
+const (
+	maxClientMessageIDLength = 64
+
+	// client_message_id unik per pengirim. Kiriman ke beberapa user membuat
+	// satu pesan per penerima, sehingga pesan kedua dan seterusnya menyimpan
+	// client_message_id + clientMessageKeySeparator + id penerima.
+	clientMessageKeySeparator = "#"
+
+	// scheduledClientMessagePrefix hanya dipakai scheduler pesan terjadwal
+	scheduledClientMessagePrefix = "scheduled:"
+)
+
+func normalizeClientMessageID(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if len(id) > maxClientMessageIDLength {
+		return "", fmt.Errorf("client_message_id must be at most %d characters", maxClientMessageIDLength)
+	}
+	return id, nil
+}
+
+// checkClientMessageID menolak client_message_id dari client yang memakai
+// format milik server
+func checkClientMessageID(id string) error {
+	id = strings.TrimSpace(id)
+	if strings.HasPrefix(id, scheduledClientMessagePrefix) {
+		return fmt.Errorf("client_message_id must not start with %q", scheduledClientMessagePrefix)
+	}
+	if strings.Contains(id, clientMessageKeySeparator) {
+		return fmt.Errorf("client_message_id must not contain %q", clientMessageKeySeparator)
+	}
+	return nil
+}
+
+// clientMessageKey adalah nilai client_message_id yang disimpan untuk pesan
+// ke-index dari satu kiriman
+func clientMessageKey(id string, index, receiverID int) string {
+	if id == "" || index == 0 {
+		return id
+	}
+	return fmt.Sprintf("%s%s%d", id, clientMessageKeySeparator, receiverID)
+}
+
+// clientMessageIDOf mengembalikan client_message_id asli dari nilai tersimpan
+func clientMessageIDOf(key string) string {
+	id, _, _ := strings.Cut(key, clientMessageKeySeparator)
+	return id
+}
+
+// duplicateSend mengembalikan pesan yang sudah tersimpan dengan
+// client_message_id yang sama dari pengirim, atau nil jika belum pernah dikirim.
+// Pesan yang sudah dihapus tetap dikembalikan agar kiriman ulang tidak
+// memunculkannya kembali.
+func (cs *ChatServiceServer) duplicateSend(senderID int, clientMessageID string) (*pb.SendMessageResponse, error) {
+	if clientMessageID == "" {
+		return nil, nil
+	}
+
+	var messages []models.Message
+	err := cs.db.Unscoped().Preload("Attachments").
+		Where("sender_id = ? AND (client_message_id = ? OR starts_with(client_message_id, ?))",
+			senderID, clientMessageID, clientMessageID+clientMessageKeySeparator).
+		Order("id ASC").
+		Find(&messages).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %v", err)
+	}
+
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	return sendResponse(messages, true), nil
+}
+
+func sendResponse(messages []models.Message, duplicate bool) *pb.SendMessageResponse {
+	res := &pb.SendMessageResponse{
+		Status:    "Sent Messages Successfully",
+		Messages:  make([]*pb.ChatMessage, 0, len(messages)),
+		Duplicate: duplicate,
+	}
+	if duplicate {
+		res.Status = "Message already sent"
+	}
+
+	for _, message := range messages {
+		res.Messages = append(res.Messages, chatMessage(message))
+	}
+
+	return res
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClientMessageKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		receivers  []int
+		wantStored []string
+	}{
+		{name: "single receiver", id: "m-1", receivers: []int{2}, wantStored: []string{"m-1"}},
+		{name: "multi receiver", id: "m-1", receivers: []int{2, 3, 4}, wantStored: []string{"m-1", "m-1#3", "m-1#4"}},
+		{name: "without client id", id: "", receivers: []int{2, 3}, wantStored: []string{"", ""}},
+		{name: "scheduled send", id: scheduledClientMessageID(7), receivers: []int{2, 3}, wantStored: []string{"scheduled:7", "scheduled:7#3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := make(map[string]bool)
+			for i, receiverID := range tt.receivers {
+				key := clientMessageKey(tt.id, i, receiverID)
+				if key != tt.wantStored[i] {
+					t.Errorf("clientMessageKey(%q, %d, %d) = %q, want %q", tt.id, i, receiverID, key, tt.wantStored[i])
+				}
+				if got := clientMessageIDOf(key); got != tt.id {
+					t.Errorf("clientMessageIDOf(%q) = %q, want %q", key, got, tt.id)
+				}
+				if tt.id != "" && seen[key] {
+					t.Errorf("key %q is stored twice", key)
+				}
+				seen[key] = true
+
+				// Semua pesan dari satu kiriman harus ditemukan duplicateSend
+				if tt.id != "" && key != tt.id && !strings.HasPrefix(key, tt.id+clientMessageKeySeparator) {
+					t.Errorf("key %q is not found by a lookup of %q", key, tt.id)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckClientMessageID(t *testing.T) {
+	tests := []struct {
+		id      string
+		wantErr bool
+	}{
+		{id: ""},
+		{id: "9b2f6c1e-0d5a-4c1b-9f3e-2a7d8e6b1c40"},
+		{id: "m-1:retry"},
+		{id: "scheduled:12", wantErr: true},
+		{id: "  scheduled:12", wantErr: true},
+		{id: "m-1#3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := checkClientMessageID(tt.id); (err != nil) != tt.wantErr {
+			t.Errorf("checkClientMessageID(%q) error = %v, want error %v", tt.id, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNormalizeClientMessageID(t *testing.T) {
+	if got, err := normalizeClientMessageID("  m-1 "); err != nil || got != "m-1" {
+		t.Errorf("normalizeClientMessageID = %q, %v, want m-1", got, err)
+	}
+
+	if _, err := normalizeClientMessageID(strings.Repeat("a", maxClientMessageIDLength+1)); err == nil {
+		t.Error("normalizeClientMessageID accepted an id longer than the limit")
+	}
+}
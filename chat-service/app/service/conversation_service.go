@@ -0,0 +1,294 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type conversationRow struct {
+	ID             int
+	Type           string
+	GroupID        *int
+	GroupName      string
+	LastMessageID  int
+	LastSenderID   int
+	LastContent    string
+	LastCreatedAt  *time.Time
+	LastActivityAt time.Time
+	UnreadCount    int
+	Retention      int
+}
+
+type participantRow struct {
+	ConversationID int
+	UserID         int
+	FirstName      string
+	LastName       string
+}
+
+func (cs *ChatServiceServer) ListConversations(ctx context.Context, req *emptypb.Empty) (*pb.ListConversationsResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	var rows []conversationRow
+	err = cs.db.Table("conversations AS c").
+		Select(`c.id, c.type, c.group_id, g.name AS group_name, c.last_message_id,
+			lm.sender_id AS last_sender_id, lm.content AS last_content, lm.created_at AS last_created_at,
+			c.last_activity_at, c.retention,
+			(SELECT COUNT(*) FROM messages AS m
+				WHERE m.conversation_id = c.id AND m.id > cm.last_read_message_id
+				AND m.sender_id <> cm.user_id AND m.deleted_at IS NULL
+				AND (m.expires_at IS NULL OR m.expires_at > NOW())) AS unread_count`).
+		Joins("JOIN conversation_members AS cm ON cm.conversation_id = c.id AND cm.user_id = ?", userID).
+		// Pesan terakhir yang sudah kedaluwarsa tidak ditampilkan walaupun
+		// sweeper belum menghapusnya
+		Joins("LEFT JOIN messages AS lm ON lm.id = c.last_message_id AND (lm.expires_at IS NULL OR lm.expires_at > NOW())").
+		Joins("LEFT JOIN groups AS g ON g.id = c.group_id").
+		Order("c.last_activity_at DESC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed get list conversation: %v", err)
+	}
+
+	conversationIDs := make([]int, len(rows))
+	for i, row := range rows {
+		conversationIDs[i] = row.ID
+	}
+
+	participants, err := cs.conversationParticipants(conversationIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	conversations := make([]*pb.Conversation, 0, len(rows))
+	for _, row := range rows {
+		conversation := &pb.Conversation{
+			Id:               int32(row.ID),
+			Type:             row.Type,
+			Name:             row.GroupName,
+			Participants:     participants[row.ID],
+			LastActivityAt:   row.LastActivityAt.Format(time.RFC3339),
+			UnreadCount:      int32(row.UnreadCount),
+			RetentionSeconds: int32(row.Retention),
+		}
+
+		if row.GroupID != nil {
+			conversation.GroupId = int32(*row.GroupID)
+		} else {
+			for _, p := range conversation.Participants {
+				if int(p.UserId) != *userID {
+					conversation.Name = strings.TrimSpace(p.FirstName + " " + p.LastName)
+				}
+			}
+		}
+
+		if row.LastMessageID != 0 && row.LastCreatedAt != nil {
+			conversation.LastMessage = &pb.LastMessage{
+				Id:        int32(row.LastMessageID),
+				SenderId:  int32(row.LastSenderID),
+				Content:   row.LastContent,
+				CreatedAt: row.LastCreatedAt.Format(time.RFC3339),
+			}
+		}
+
+		conversations = append(conversations, conversation)
+	}
+
+	return &pb.ListConversationsResponse{Conversations: conversations}, nil
+}
+
+func (cs *ChatServiceServer) conversationParticipants(conversationIDs []int) (map[int][]*pb.Participant, error) {
+	result := make(map[int][]*pb.Participant)
+	if len(conversationIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []participantRow
+	err := cs.db.Table("conversation_members AS cm").
+		Select("cm.conversation_id, cm.user_id, u.first_name, u.last_name").
+		Joins("JOIN users AS u ON u.id = cm.user_id").
+		Where("cm.conversation_id IN ?", conversationIDs).
+		Order("cm.id ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation participants: %v", err)
+	}
+
+	for _, row := range rows {
+		result[row.ConversationID] = append(result[row.ConversationID], &pb.Participant{
+			UserId:    int32(row.UserID),
+			FirstName: row.FirstName,
+			LastName:  row.LastName,
+		})
+	}
+
+	return result, nil
+}
+
+// directConversation mengambil percakapan 1:1 antara dua user,
+// dan membuatnya jika belum ada
+func directConversation(tx *gorm.DB, userA, userB int) (*models.Conversation, error) {
+	key := models.DirectConversationKey(userA, userB)
+	conversation := models.Conversation{
+		Type:           models.ConversationDirect,
+		DirectKey:      &key,
+		LastActivityAt: time.Now(),
+	}
+
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&conversation).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create conversation: %v", err)
+	}
+
+	if conversation.ID == 0 {
+		if err := tx.Where("direct_key = ?", key).First(&conversation).Error; err != nil {
+			return nil, fmt.Errorf("failed to get conversation: %v", err)
+		}
+		return &conversation, nil
+	}
+
+	members := []models.ConversationMember{
+		{ConversationID: conversation.ID, UserID: userA},
+	}
+	if userB != userA {
+		members = append(members, models.ConversationMember{ConversationID: conversation.ID, UserID: userB})
+	}
+
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to create conversation members: %v", err)
+	}
+
+	return &conversation, nil
+}
+
+// groupConversation mengambil percakapan milik group,
+// dan membuatnya jika belum ada
+func groupConversation(tx *gorm.DB, groupID int) (*models.Conversation, error) {
+	conversation := models.Conversation{
+		Type:           models.ConversationGroup,
+		GroupID:        &groupID,
+		LastActivityAt: time.Now(),
+	}
+
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&conversation).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create conversation: %v", err)
+	}
+
+	if conversation.ID == 0 {
+		if err := tx.Where("group_id = ?", groupID).First(&conversation).Error; err != nil {
+			return nil, fmt.Errorf("failed to get conversation: %v", err)
+		}
+		return &conversation, nil
+	}
+
+	if err := syncGroupConversationMembers(tx, conversation.ID, groupID); err != nil {
+		return nil, err
+	}
+
+	return &conversation, nil
+}
+
+// syncGroupConversationMembers menyamakan anggota percakapan dengan anggota group
+func syncGroupConversationMembers(tx *gorm.DB, conversationID, groupID int) error {
+	err := tx.Exec(`INSERT INTO conversation_members (conversation_id, user_id, last_read_message_id, created_at)
+		SELECT ?, gm.user_id, 0, NOW() FROM group_members AS gm WHERE gm.group_id = ?
+		ON CONFLICT DO NOTHING`, conversationID, groupID).Error
+	if err != nil {
+		return fmt.Errorf("failed to sync conversation members: %v", err)
+	}
+
+	err = tx.Exec(`DELETE FROM conversation_members
+		WHERE conversation_id = ? AND user_id NOT IN (SELECT user_id FROM group_members WHERE group_id = ?)`,
+		conversationID, groupID).Error
+	if err != nil {
+		return fmt.Errorf("failed to sync conversation members: %v", err)
+	}
+
+	return nil
+}
+
+// touchConversation memperbarui pesan terakhir percakapan. Pesan milik
+// pengirim sendiri langsung dianggap sudah dibaca.
+func touchConversation(tx *gorm.DB, message models.Message) error {
+	err := tx.Model(&models.Conversation{}).
+		Where("id = ?", message.ConversationID).
+		Updates(map[string]interface{}{
+			"last_message_id":  message.ID,
+			"last_activity_at": message.CreatedAt,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update conversation: %v", err)
+	}
+
+	err = tx.Model(&models.ConversationMember{}).
+		Where("conversation_id = ? AND user_id = ?", message.ConversationID, message.SenderID).
+		Update("last_read_message_id", message.ID).Error
+	if err != nil {
+		return fmt.Errorf("failed to update conversation member: %v", err)
+	}
+
+	return nil
+}
+
+// findConversation mencari percakapan milik user berdasarkan conversation_id,
+// peer_id atau group_id. Mengembalikan nil jika percakapan 1:1 belum pernah ada.
+func (cs *ChatServiceServer) findConversation(userID int, conversationID, peerID, groupID int32) (*models.Conversation, error) {
+	var conversation models.Conversation
+	var err error
+
+	switch {
+	case conversationID != 0:
+		err = cs.db.First(&conversation, conversationID).Error
+	case peerID != 0:
+		err = cs.db.Where("direct_key = ?", models.DirectConversationKey(userID, int(peerID))).First(&conversation).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+	case groupID != 0:
+		err = cs.db.Where("group_id = ?", groupID).First(&conversation).Error
+	default:
+		return nil, fmt.Errorf("conversation_id, peer_id or group_id is required")
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("conversation not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %v", err)
+	}
+
+	if err := cs.checkConversationMember(conversation.ID, userID); err != nil {
+		return nil, err
+	}
+
+	return &conversation, nil
+}
+
+func (cs *ChatServiceServer) checkConversationMember(conversationID, userID int) error {
+	var count int64
+	err := cs.db.Model(&models.ConversationMember{}).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to get conversation member: %v", err)
+	}
+
+	if count == 0 {
+		return fmt.Errorf("you are not a participant of this conversation")
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	EventMessageEdited  = "message_edited"
+	EventMessageDeleted = "message_deleted"
+)
+
+func (cs *ChatServiceServer) EditMessage(ctx context.Context, req *pb.EditMessageRequest) (*pb.ChatMessage, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		return nil, fmt.Errorf("content is required")
+	}
+
+	message, err := cs.ownMessage(int(req.MessageId), *userID)
+	if err != nil {
+		return nil, err
+	}
+
+	window := time.Duration(cs.cfg.Chat.EditWindowMinutes) * time.Minute
+	if window > 0 && time.Since(message.CreatedAt) > window {
+		return nil, fmt.Errorf("message can only be edited within %d minutes", cs.cfg.Chat.EditWindowMinutes)
+	}
+
+	now := time.Now()
+	err = cs.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&models.MessageEdit{
+			MessageID:       message.ID,
+			EditorID:        *userID,
+			PreviousContent: message.Content,
+			EditedAt:        now,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to save message edit: %v", err)
+		}
+
+		// Event edit sebelumnya sudah tidak berlaku, cukup event edit terbaru di Sync
+		err = tx.Where("message_id = ? AND type = ?", message.ID, EventMessageEdited).Delete(&models.UserEvent{}).Error
+		if err != nil {
+			return fmt.Errorf("failed to update events: %v", err)
+		}
+
+		return tx.Model(message).Updates(map[string]interface{}{
+			"content":   content,
+			"edited_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to edit message: %v", err)
+	}
+
+	event := messageEvent(*message)
+	event.Type = EventMessageEdited
+	event.Timestamp = now.Format(time.RFC3339)
+	cs.notifyParticipants(ctx, message.ConversationID, *userID, event)
+
+	messages, err := cs.chatMessages(*userID, []models.Message{*message})
+	if err != nil {
+		return nil, err
+	}
+
+	return messages[0], nil
+}
+
+func (cs *ChatServiceServer) DeleteMessage(ctx context.Context, req *pb.DeleteMessageRequest) (*pb.DeleteMessageResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	message, err := cs.ownMessage(int(req.MessageId), *userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(message).Error; err != nil {
+			return err
+		}
+
+		// Isi pesan yang dihapus tidak boleh lagi muncul lewat Sync
+		if err := tx.Where("message_id = ?", message.ID).Delete(&models.UserEvent{}).Error; err != nil {
+			return err
+		}
+
+		return refreshLastMessage(tx, message.ConversationID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete message: %v", err)
+	}
+
+	cs.notifyParticipants(ctx, message.ConversationID, *userID, &pb.StreamMessagesResponse{
+		Type:           EventMessageDeleted,
+		MessageId:      int32(message.ID),
+		ConversationId: int32(message.ConversationID),
+		SenderId:       int32(message.SenderID),
+		GroupId:        int32(message.GroupId),
+		Timestamp:      time.Now().Format(time.RFC3339),
+	})
+
+	return &pb.DeleteMessageResponse{Status: "Message deleted successfully"}, nil
+}
+
+// ownMessage mengambil pesan yang belum dihapus atau kedaluwarsa dan
+// memastikan user adalah pengirimnya
+func (cs *ChatServiceServer) ownMessage(messageID, userID int) (*models.Message, error) {
+	var message models.Message
+	err := cs.db.Scopes(notExpired).First(&message, messageID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("message not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get message: %v", err)
+	}
+
+	if message.SenderID != userID {
+		return nil, fmt.Errorf("only the sender can change this message")
+	}
+
+	return &message, nil
+}
+
+// notifyParticipants mengirim event ke semua peserta percakapan kecuali user itu sendiri
+func (cs *ChatServiceServer) notifyParticipants(ctx context.Context, conversationID, exceptUserID int, event *pb.StreamMessagesResponse) {
+	var memberIDs []int
+	err := cs.db.Model(&models.ConversationMember{}).
+		Where("conversation_id = ? AND user_id <> ?", conversationID, exceptUserID).
+		Pluck("user_id", &memberIDs).Error
+	if err != nil {
+		return
+	}
+
+	cs.deliverAll(ctx, memberIDs, event)
+}
+
+// refreshLastMessage menunjuk pesan terakhir percakapan ke pesan terbaru yang
+// belum dihapus atau kedaluwarsa
+func refreshLastMessage(tx *gorm.DB, conversationID int) error {
+	var last models.Message
+	err := tx.Scopes(notExpired).Where("conversation_id = ?", conversationID).Order("id DESC").Limit(1).Find(&last).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Conversation{}).
+		Where("id = ?", conversationID).
+		Update("last_message_id", last.ID).Error
+}
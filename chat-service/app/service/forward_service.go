@@ -0,0 +1,186 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const maxForwardTargets = 20
+
+// ForwardMessage menyalin pesan beserta lampirannya ke percakapan lain milik
+// pengirim. Pesan baru menyimpan id dan pengirim pesan asli, sehingga pesan
+// yang diteruskan berulang kali tetap menunjuk sumber pertamanya.
+func (cs *ChatServiceServer) ForwardMessage(ctx context.Context, req *pb.ForwardMessageRequest) (*pb.ForwardMessageResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	if req.MessageId == 0 {
+		return nil, fmt.Errorf("message_id is required")
+	}
+
+	conversationIDs := uniqueIDs(req.ConversationIds, 0)
+	if len(conversationIDs) == 0 {
+		return nil, fmt.Errorf("conversation_ids is required")
+	}
+	if len(conversationIDs) > maxForwardTargets {
+		return nil, fmt.Errorf("a message can be forwarded to at most %d conversations", maxForwardTargets)
+	}
+
+	source, err := cs.forwardSource(*userID, int(req.MessageId))
+	if err != nil {
+		return nil, err
+	}
+
+	originMessageID, originSenderID := source.ID, source.SenderID
+	if source.OriginMessageID != 0 {
+		originMessageID, originSenderID = source.OriginMessageID, source.OriginSenderID
+	}
+
+	type forwarded struct {
+		message    models.Message
+		recipients []int
+	}
+	var results []forwarded
+
+	err = cs.db.Transaction(func(tx *gorm.DB) error {
+		for _, conversationID := range conversationIDs {
+			var conversation models.Conversation
+			err := tx.First(&conversation, conversationID).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("conversation %d not found", conversationID)
+			} else if err != nil {
+				return fmt.Errorf("failed to get conversation: %v", err)
+			}
+
+			var memberIDs []int
+			err = tx.Model(&models.ConversationMember{}).
+				Where("conversation_id = ?", conversation.ID).
+				Pluck("user_id", &memberIDs).Error
+			if err != nil {
+				return fmt.Errorf("failed to get conversation members: %v", err)
+			}
+			if !containsID(memberIDs, *userID) {
+				return fmt.Errorf("you are not a participant of conversation %d", conversationID)
+			}
+
+			message := models.Message{
+				ConversationID:  conversation.ID,
+				SenderID:        *userID,
+				Content:         source.Content,
+				ContentType:     source.ContentType,
+				OriginMessageID: originMessageID,
+				OriginSenderID:  originSenderID,
+				CreatedAt:       time.Now(),
+			}
+
+			var recipients []int
+			for _, memberID := range memberIDs {
+				if memberID != *userID {
+					recipients = append(recipients, memberID)
+				}
+			}
+
+			if conversation.GroupID != nil {
+				message.GroupId = *conversation.GroupID
+			} else if len(recipients) > 0 {
+				message.RecieverID = recipients[0]
+			} else {
+				// Percakapan dengan diri sendiri
+				message.RecieverID = *userID
+			}
+
+			// Salinan tidak boleh hidup lebih lama dari pesan sumbernya
+			applyRetention(&message, &conversation)
+			if source.ExpiresAt != nil && (message.ExpiresAt == nil || source.ExpiresAt.Before(*message.ExpiresAt)) {
+				message.ExpiresAt = source.ExpiresAt
+			}
+
+			if err := tx.Create(&message).Error; err != nil {
+				return fmt.Errorf("failed to save messages to database: %v", err)
+			}
+
+			if err := copyAttachments(tx, &message, source.Attachments); err != nil {
+				return err
+			}
+
+			if conversation.GroupID != nil {
+				err := tx.Model(&models.Group{}).Where("id = ?", *conversation.GroupID).Update("updated_at", message.CreatedAt).Error
+				if err != nil {
+					return fmt.Errorf("failed to update group: %v", err)
+				}
+			}
+
+			if err := touchConversation(tx, message); err != nil {
+				return err
+			}
+
+			if err := createReceipts(tx, message.ID, recipients); err != nil {
+				return err
+			}
+
+			results = append(results, forwarded{message: message, recipients: recipients})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]models.Message, 0, len(results))
+	for _, result := range results {
+		cs.deliverAll(ctx, result.recipients, messageEvent(result.message))
+		messages = append(messages, result.message)
+	}
+
+	decorated, err := cs.chatMessages(*userID, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ForwardMessageResponse{Messages: decorated}, nil
+}
+
+// forwardSource mengambil pesan yang akan diteruskan. Pesan yang tidak bisa
+// dibaca user dianggap tidak ada agar keberadaannya tidak bocor.
+func (cs *ChatServiceServer) forwardSource(userID, messageID int) (*models.Message, error) {
+	var source models.Message
+	err := cs.db.Scopes(notExpired).Preload("Attachments").First(&source, messageID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("message not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get message: %v", err)
+	}
+
+	if err := cs.checkConversationMember(source.ConversationID, userID); err != nil {
+		return nil, fmt.Errorf("message not found")
+	}
+
+	return &source, nil
+}
+
+// copyAttachments membuat salinan metadata lampiran untuk pesan baru.
+// Blob tidak disalin; salinan memakai StorageKey yang sama.
+func copyAttachments(tx *gorm.DB, message *models.Message, attachments []models.Attachment) error {
+	for _, attachment := range attachments {
+		clone := attachment
+		clone.ID = 0
+		clone.MessageID = message.ID
+		clone.CreatedAt = time.Now()
+		if err := tx.Create(&clone).Error; err != nil {
+			return fmt.Errorf("failed to copy attachment: %v", err)
+		}
+		message.Attachments = append(message.Attachments, clone)
+	}
+
+	return nil
+}
@@ -0,0 +1,400 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"gorm.io/gorm"
+)
+
+func (cs *ChatServiceServer) CreateGroup(ctx context.Context, req *pb.CreateGroupRequest) (*pb.Group, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("group name is required")
+	}
+
+	memberIDs := uniqueIDs(req.MemberIds, *userID)
+	if err := cs.checkUsersExist(memberIDs); err != nil {
+		return nil, err
+	}
+
+	group := models.Group{
+		Name:    name,
+		OwnerID: *userID,
+		Members: []models.GroupMember{{UserID: *userID, Role: models.GroupRoleAdmin}},
+	}
+	for _, id := range memberIDs {
+		group.Members = append(group.Members, models.GroupMember{UserID: id, Role: models.GroupRoleMember})
+	}
+
+	err = cs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&group).Error; err != nil {
+			return fmt.Errorf("failed to create group: %v", err)
+		}
+
+		_, err := groupConversation(tx, group.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cs.groupResponse(group.ID)
+}
+
+func (cs *ChatServiceServer) AddMembers(ctx context.Context, req *pb.AddMembersRequest) (*pb.Group, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	if err := cs.checkGroupAdmin(int(req.GroupId), *userID); err != nil {
+		return nil, err
+	}
+
+	memberIDs := uniqueIDs(req.MemberIds, *userID)
+	if len(memberIDs) == 0 {
+		return nil, fmt.Errorf("member_ids is required")
+	}
+	if err := cs.checkUsersExist(memberIDs); err != nil {
+		return nil, err
+	}
+
+	var existing []int
+	err = cs.db.Model(&models.GroupMember{}).
+		Where("group_id = ? AND user_id IN ?", req.GroupId, memberIDs).
+		Pluck("user_id", &existing).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group members: %v", err)
+	}
+
+	var members []models.GroupMember
+	for _, id := range memberIDs {
+		if !containsID(existing, id) {
+			members = append(members, models.GroupMember{GroupID: int(req.GroupId), UserID: id, Role: models.GroupRoleMember})
+		}
+	}
+
+	if len(members) > 0 {
+		err = cs.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&members).Error; err != nil {
+				return fmt.Errorf("failed to add group members: %v", err)
+			}
+
+			conversation, err := groupConversation(tx, int(req.GroupId))
+			if err != nil {
+				return err
+			}
+			return syncGroupConversationMembers(tx, conversation.ID, int(req.GroupId))
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cs.groupResponse(int(req.GroupId))
+}
+
+func (cs *ChatServiceServer) RemoveMember(ctx context.Context, req *pb.RemoveMemberRequest) (*pb.GroupResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	if err := cs.checkGroupAdmin(int(req.GroupId), *userID); err != nil {
+		return nil, err
+	}
+
+	if int(req.UserId) == *userID {
+		return nil, fmt.Errorf("use LeaveGroup to leave a group")
+	}
+
+	if err := cs.removeGroupMember(int(req.GroupId), int(req.UserId)); err != nil {
+		return nil, err
+	}
+
+	return &pb.GroupResponse{Status: "Member removed successfully"}, nil
+}
+
+func (cs *ChatServiceServer) LeaveGroup(ctx context.Context, req *pb.LeaveGroupRequest) (*pb.GroupResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	if err := cs.removeGroupMember(int(req.GroupId), *userID); err != nil {
+		return nil, err
+	}
+
+	return &pb.GroupResponse{Status: "Left group successfully"}, nil
+}
+
+func (cs *ChatServiceServer) ListGroups(ctx context.Context, req *emptypb.Empty) (*pb.ListGroupsResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	var groupIDs []int
+	err = cs.db.Table("group_members AS gm").
+		Joins("JOIN groups AS g ON g.id = gm.group_id AND g.deleted_at IS NULL").
+		Where("gm.user_id = ?", userID).
+		Order("g.updated_at DESC").
+		Pluck("gm.group_id", &groupIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed get list group: %v", err)
+	}
+
+	groups := make([]*pb.Group, 0, len(groupIDs))
+	for _, id := range groupIDs {
+		group, err := cs.groupResponse(id)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	return &pb.ListGroupsResponse{Groups: groups}, nil
+}
+
+func (cs *ChatServiceServer) sendGroupMessage(ctx context.Context, senderID int, req *pb.SendMessageRequest) (*pb.SendMessageResponse, error) {
+
+	memberIDs, err := cs.groupMemberIDs(int(req.GroupId))
+	if err != nil {
+		return nil, err
+	}
+
+	if !containsID(memberIDs, senderID) {
+		return nil, fmt.Errorf("you are not a member of this group")
+	}
+
+	var recipients []int
+	for _, memberID := range memberIDs {
+		if memberID != senderID {
+			recipients = append(recipients, memberID)
+		}
+	}
+
+	message := models.Message{
+		SenderID:        senderID,
+		GroupId:         int(req.GroupId),
+		Content:         req.Content,
+		ContentType:     models.ContentText,
+		ClientMessageID: req.ClientMessageId,
+		CreatedAt:       time.Now(),
+	}
+
+	var mentions []models.Mention
+
+	err = cs.db.Transaction(func(tx *gorm.DB) error {
+		conversation, err := groupConversation(tx, int(req.GroupId))
+		if err != nil {
+			return err
+		}
+
+		message.ConversationID = conversation.ID
+		applyRetention(&message, conversation)
+		if err := applyReply(tx, &message, req); err != nil {
+			return err
+		}
+
+		if err := tx.Create(&message).Error; err != nil {
+			return fmt.Errorf("failed to save messages to database: %v", err)
+		}
+
+		if err := linkAttachments(tx, &message, req.AttachmentIds); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Group{}).Where("id = ?", req.GroupId).Update("updated_at", message.CreatedAt).Error; err != nil {
+			return fmt.Errorf("failed to update group: %v", err)
+		}
+
+		if err := touchConversation(tx, message); err != nil {
+			return err
+		}
+
+		if err := createReceipts(tx, message.ID, recipients); err != nil {
+			return err
+		}
+
+		mentions, err = recordMentions(tx, message)
+		return err
+	})
+	if err != nil {
+		if res, dupErr := cs.duplicateSend(senderID, req.ClientMessageId); res != nil && dupErr == nil {
+			return res, nil
+		}
+		return nil, err
+	}
+
+	cs.deliverAll(ctx, recipients, messageEvent(message))
+	cs.notifyMentions(ctx, message, mentions)
+
+	return sendResponse([]models.Message{message}, false), nil
+}
+
+// removeGroupMember menghapus user dari group. Jika admin terakhir keluar,
+// member paling lama diangkat menjadi admin, dan group kosong akan dihapus.
+func (cs *ChatServiceServer) removeGroupMember(groupID, userID int) error {
+	return cs.db.Transaction(func(tx *gorm.DB) error {
+		var member models.GroupMember
+		err := tx.Where("group_id = ? AND user_id = ?", groupID, userID).First(&member).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("user is not a member of this group")
+		} else if err != nil {
+			return fmt.Errorf("failed to get group member: %v", err)
+		}
+
+		if err := tx.Delete(&member).Error; err != nil {
+			return fmt.Errorf("failed to remove group member: %v", err)
+		}
+
+		conversation, err := groupConversation(tx, groupID)
+		if err != nil {
+			return err
+		}
+		if err := syncGroupConversationMembers(tx, conversation.ID, groupID); err != nil {
+			return err
+		}
+
+		var remaining []models.GroupMember
+		if err := tx.Where("group_id = ?", groupID).Order("created_at ASC").Find(&remaining).Error; err != nil {
+			return fmt.Errorf("failed to get group members: %v", err)
+		}
+
+		if len(remaining) == 0 {
+			return tx.Delete(&models.Group{}, groupID).Error
+		}
+
+		for _, m := range remaining {
+			if m.Role == models.GroupRoleAdmin {
+				return nil
+			}
+		}
+
+		successor := remaining[0]
+		if err := tx.Model(&successor).Update("role", models.GroupRoleAdmin).Error; err != nil {
+			return fmt.Errorf("failed to promote group admin: %v", err)
+		}
+		return tx.Model(&models.Group{}).Where("id = ?", groupID).Update("owner_id", successor.UserID).Error
+	})
+}
+
+func (cs *ChatServiceServer) checkGroupAdmin(groupID, userID int) error {
+	var member models.GroupMember
+	err := cs.db.Where("group_id = ? AND user_id = ?", groupID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("you are not a member of this group")
+	} else if err != nil {
+		return fmt.Errorf("failed to get group member: %v", err)
+	}
+
+	if member.Role != models.GroupRoleAdmin {
+		return fmt.Errorf("only group admins can manage members")
+	}
+
+	return nil
+}
+
+func (cs *ChatServiceServer) checkUsersExist(userIDs []int) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	var count int64
+	err := cs.db.Table("users").Where("id IN ? AND deleted_at IS NULL", userIDs).Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to check users: %v", err)
+	}
+
+	if int(count) != len(userIDs) {
+		return fmt.Errorf("one or more users not found")
+	}
+
+	return nil
+}
+
+func (cs *ChatServiceServer) groupMemberIDs(groupID int) ([]int, error) {
+	var group models.Group
+	err := cs.db.First(&group, groupID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("group not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get group: %v", err)
+	}
+
+	var memberIDs []int
+	err = cs.db.Model(&models.GroupMember{}).Where("group_id = ?", groupID).Pluck("user_id", &memberIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group members: %v", err)
+	}
+
+	return memberIDs, nil
+}
+
+func (cs *ChatServiceServer) groupResponse(groupID int) (*pb.Group, error) {
+	var group models.Group
+	err := cs.db.First(&group, groupID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("group not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get group: %v", err)
+	}
+
+	var members []*pb.GroupMember
+	err = cs.db.Table("group_members AS gm").
+		Select("gm.user_id, u.first_name, gm.role").
+		Joins("JOIN users AS u ON u.id = gm.user_id").
+		Where("gm.group_id = ?", groupID).
+		Order("gm.created_at ASC").
+		Scan(&members).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group members: %v", err)
+	}
+
+	return &pb.Group{
+		Id:        int32(group.ID),
+		Name:      group.Name,
+		OwnerId:   int32(group.OwnerID),
+		Members:   members,
+		CreatedAt: group.CreatedAt.Format(time.RFC3339),
+	}, nil
+}
+
+// uniqueIDs menghapus id duplikat dan id milik user sendiri
+func uniqueIDs(ids []int32, exclude int) []int {
+	var result []int
+	for _, id := range ids {
+		if int(id) != exclude && id > 0 && !containsID(result, int(id)) {
+			result = append(result, int(id))
+		}
+	}
+	return result
+}
+
+func containsID(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
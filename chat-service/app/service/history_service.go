@@ -0,0 +1,152 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 100
+)
+
+func (cs *ChatServiceServer) ListMessageHistory(ctx context.Context, req *pb.ListMessageHistoryRequest) (*pb.ListMessageHistoryResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	conversation, err := cs.findConversation(*userID, req.ConversationId, req.PeerId, req.GroupId)
+	if err != nil {
+		return nil, err
+	}
+	if conversation == nil {
+		return &pb.ListMessageHistoryResponse{Messages: []*pb.ChatMessage{}}, nil
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	} else if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	after := req.Direction == "after"
+	if req.Direction != "" && req.Direction != "before" && !after {
+		return nil, fmt.Errorf("direction must be before or after")
+	}
+
+	// Balasan di dalam thread hanya tampil lewat ListThread. Pesan yang sudah
+	// kedaluwarsa tidak ditampilkan walaupun sweeper belum menghapusnya.
+	query := cs.db.Model(&models.Message{}).
+		Where("conversation_id = ? AND thread_root_id = 0", conversation.ID).
+		Scopes(notExpired)
+
+	if req.Cursor != "" {
+		cursorID, cursorTime, err := parseCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case cursorTime != nil && after:
+			query = query.Where("created_at > ?", *cursorTime)
+		case cursorTime != nil:
+			query = query.Where("created_at < ?", *cursorTime)
+		case after:
+			query = query.Where("id > ?", cursorID)
+		default:
+			query = query.Where("id < ?", cursorID)
+		}
+	}
+
+	if after {
+		query = query.Order("id ASC")
+	} else {
+		query = query.Order("id DESC")
+	}
+
+	var messages []models.Message
+	if err := query.Limit(limit + 1).Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to get messages: %v", err)
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	// Pesan selalu dikembalikan berurutan dari yang paling lama
+	if !after {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
+	chatMessages, err := cs.chatMessages(*userID, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.ListMessageHistoryResponse{
+		Messages: chatMessages,
+		HasMore:  hasMore,
+	}
+
+	if hasMore {
+		if after {
+			res.NextCursor = strconv.Itoa(messages[len(messages)-1].ID)
+		} else {
+			res.NextCursor = strconv.Itoa(messages[0].ID)
+		}
+	}
+
+	return res, nil
+}
+
+// parseCursor menerima id pesan atau timestamp RFC3339
+func parseCursor(cursor string) (int, *time.Time, error) {
+	if id, err := strconv.Atoi(cursor); err == nil {
+		return id, nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, cursor)
+	if err != nil {
+		return 0, nil, fmt.Errorf("cursor must be a message id or RFC3339 timestamp")
+	}
+
+	return 0, &t, nil
+}
+
+func chatMessage(message models.Message) *pb.ChatMessage {
+	var editedAt string
+	if message.EditedAt != nil {
+		editedAt = message.EditedAt.Format(time.RFC3339)
+	}
+
+	return &pb.ChatMessage{
+		Id:               int32(message.ID),
+		ConversationId:   int32(message.ConversationID),
+		SenderId:         int32(message.SenderID),
+		ReceiverId:       int32(message.RecieverID),
+		GroupId:          int32(message.GroupId),
+		Content:          message.Content,
+		CreatedAt:        message.CreatedAt.Format(time.RFC3339),
+		EditedAt:         editedAt,
+		ReplyToMessageId: int32(message.ReplyToMessageID),
+		ThreadRootId:     int32(message.ThreadRootID),
+		Attachments:      attachmentProtos(message.Attachments),
+		ContentType:      message.ContentType,
+		ClientMessageId:  clientMessageIDOf(message.ClientMessageID),
+		ExpiresAt:        formatOptionalTime(message.ExpiresAt),
+
+		ForwardedFromMessageId: int32(message.OriginMessageID),
+		ForwardedFromSenderId:  int32(message.OriginSenderID),
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"bytes"
+	"chat-service/app/media"
+	"chat-service/app/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+const (
+	EventAttachmentReady = "attachment_ready"
+
+	mediaPollInterval = 30 * time.Second
+	// Gambar yang berstatus processing lebih lama dari ini dianggap
+	// ditinggal worker yang mati dan akan diproses ulang
+	mediaStaleAfter = 5 * time.Minute
+)
+
+// processableImages adalah MIME type gambar yang bisa di-decode worker dan
+// dibersihkan dari lokasi EXIF (GIF tidak memiliki EXIF). Gambar lain ditolak
+// saat diunggah.
+var processableImages = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// RunMediaWorker memproses gambar yang baru diunggah: menghapus lokasi EXIF,
+// mencatat dimensi dan membuat thumbnail. Aman dijalankan di beberapa
+// replica karena setiap gambar diklaim dengan FOR UPDATE SKIP LOCKED.
+func (cs *ChatServiceServer) RunMediaWorker(ctx context.Context) {
+	ticker := time.NewTicker(mediaPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for cs.processNextImage(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-cs.mediaWake:
+		}
+	}
+}
+
+// wakeMediaWorker membangunkan worker tanpa menunggu interval polling
+func (cs *ChatServiceServer) wakeMediaWorker() {
+	select {
+	case cs.mediaWake <- struct{}{}:
+	default:
+	}
+}
+
+// processNextImage mengklaim satu gambar dan memprosesnya,
+// mengembalikan false jika tidak ada gambar yang menunggu
+func (cs *ChatServiceServer) processNextImage(ctx context.Context) bool {
+	var attachment models.Attachment
+	err := cs.db.Raw(`UPDATE attachments SET status = ?, updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM attachments
+			WHERE status = ? OR (status = ? AND updated_at < ?)
+			ORDER BY id ASC LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		models.AttachmentProcessing, models.AttachmentPending, models.AttachmentProcessing,
+		time.Now().Add(-mediaStaleAfter)).
+		Scan(&attachment).Error
+	if err != nil {
+		log.Printf("Error claiming image for processing: %v", err)
+		return false
+	}
+	if attachment.ID == 0 {
+		return false
+	}
+
+	updates, err := cs.processImage(ctx, attachment)
+	if err != nil {
+		log.Printf("Error processing attachment %d: %v", attachment.ID, err)
+		updates["status"] = models.AttachmentFailed
+	} else {
+		updates["status"] = models.AttachmentReady
+	}
+
+	// Salinan lampiran untuk pesan ke beberapa penerima memakai blob yang sama
+	err = cs.db.Model(&models.Attachment{}).Where("storage_key = ?", attachment.StorageKey).Updates(updates).Error
+	if err != nil {
+		log.Printf("Error saving processed attachment %d: %v", attachment.ID, err)
+		return true
+	}
+
+	cs.notifyAttachmentReady(ctx, attachment.StorageKey)
+	return true
+}
+
+// processImage mengembalikan kolom yang perlu diperbarui. Jika terjadi error
+// setelah lokasi EXIF dihapus, perubahan ukuran dan checksum tetap disimpan.
+func (cs *ChatServiceServer) processImage(ctx context.Context, attachment models.Attachment) (map[string]interface{}, error) {
+	updates := map[string]interface{}{}
+
+	blob, err := cs.store.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		return updates, err
+	}
+	data, err := io.ReadAll(blob)
+	blob.Close()
+	if err != nil {
+		return updates, err
+	}
+
+	data, orientation, changed := media.StripLocation(data, attachment.MimeType)
+	if changed {
+		if err := cs.store.Put(ctx, attachment.StorageKey, bytes.NewReader(data), int64(len(data)), attachment.MimeType); err != nil {
+			return updates, fmt.Errorf("failed to store stripped image: %v", err)
+		}
+
+		sum := sha256.Sum256(data)
+		updates["size"] = int64(len(data))
+		updates["checksum"] = hex.EncodeToString(sum[:])
+	}
+
+	result, err := media.Process(bytes.NewReader(data), orientation, cs.cfg.Storage.ThumbnailSize)
+	if err != nil {
+		return updates, err
+	}
+
+	thumbnailKey := attachment.StorageKey + "_thumb"
+	err = cs.store.Put(ctx, thumbnailKey, bytes.NewReader(result.Thumbnail), int64(len(result.Thumbnail)), media.ThumbnailMimeType)
+	if err != nil {
+		return updates, fmt.Errorf("failed to store thumbnail: %v", err)
+	}
+
+	updates["width"] = result.Width
+	updates["height"] = result.Height
+	updates["thumbnail_key"] = thumbnailKey
+	updates["thumbnail_size"] = int64(len(result.Thumbnail))
+	return updates, nil
+}
+
+// notifyAttachmentReady mengirim lampiran yang sudah diproses ke semua
+// peserta percakapan pesan yang memakainya, termasuk pengirimnya
+func (cs *ChatServiceServer) notifyAttachmentReady(ctx context.Context, storageKey string) {
+	var messages []models.Message
+	err := cs.db.Preload("Attachments").
+		Where("id IN (SELECT message_id FROM attachments WHERE storage_key = ?)", storageKey).
+		Find(&messages).Error
+	if err != nil {
+		log.Printf("Error getting messages of attachment: %v", err)
+		return
+	}
+
+	for _, message := range messages {
+		event := messageEvent(message)
+		event.Type = EventAttachmentReady
+		event.Timestamp = time.Now().Format(time.RFC3339)
+		cs.notifyParticipants(ctx, message.ConversationID, 0, event)
+	}
+}
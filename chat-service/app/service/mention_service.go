@@ -0,0 +1,190 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// EventMention dikirim ke user yang disebut di sebuah pesan, terpisah dari
+	// event message agar client bisa memberi notifikasi khusus
+	EventMention = "mention"
+
+	PriorityHigh = "high"
+
+	maxMentionsPerMessage = 20
+)
+
+// mentionPattern mencari @handle atau @user_id. Karakter sebelum @ tidak boleh
+// huruf, angka, titik atau @ agar alamat email tidak terbaca sebagai mention.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@])@([\w.-]+)`)
+
+func (cs *ChatServiceServer) ListMentions(ctx context.Context, req *pb.ListMentionsRequest) (*pb.ListMentionsResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	} else if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	// Hanya pesan yang masih bisa dibaca: belum dihapus, belum kedaluwarsa
+	// dan user masih menjadi peserta percakapannya
+	query := cs.db.Model(&models.Message{}).
+		Joins("JOIN mentions AS mn ON mn.message_id = messages.id AND mn.user_id = ?", userID).
+		Joins("JOIN conversation_members AS cm ON cm.conversation_id = messages.conversation_id AND cm.user_id = ?", userID).
+		Scopes(notExpired)
+	if req.Cursor != "" {
+		cursorID, err := strconv.Atoi(req.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("cursor must be a message id")
+		}
+		query = query.Where("messages.id < ?", cursorID)
+	}
+
+	var messages []models.Message
+	if err := query.Order("messages.id DESC").Limit(limit + 1).Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to get mentions: %v", err)
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	decorated, err := cs.chatMessages(*userID, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.ListMentionsResponse{Messages: decorated, HasMore: hasMore}
+	if hasMore {
+		res.NextCursor = strconv.Itoa(messages[len(messages)-1].ID)
+	}
+
+	return res, nil
+}
+
+// parseMentions mengambil handle yang disebut di isi pesan, huruf kecil dan
+// tanpa duplikat, paling banyak maxMentionsPerMessage
+func parseMentions(content string) []string {
+	var handles []string
+	seen := make(map[string]bool)
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		handle := strings.ToLower(strings.TrimRight(match[1], ".-"))
+		if handle == "" || seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+		if len(handles) == maxMentionsPerMessage {
+			break
+		}
+	}
+
+	return handles
+}
+
+// mentionHandle adalah bagian email sebelum @, dipakai sebagai @handle user
+func mentionHandle(email string) string {
+	handle, _, _ := strings.Cut(email, "@")
+	return strings.ToLower(handle)
+}
+
+// recordMentions menyimpan user yang disebut di pesan. Hanya peserta
+// percakapan yang bisa disebut; handle yang dimiliki lebih dari satu peserta
+// diabaikan karena tidak jelas siapa yang dimaksud, gunakan @user_id.
+func recordMentions(tx *gorm.DB, message models.Message) ([]models.Mention, error) {
+	handles := parseMentions(message.Content)
+	if len(handles) == 0 {
+		return nil, nil
+	}
+
+	var members []struct {
+		ID    int
+		Email string
+	}
+	err := tx.Table("conversation_members AS cm").
+		Select("u.id, u.email").
+		Joins("JOIN users AS u ON u.id = cm.user_id").
+		Where("cm.conversation_id = ? AND u.deleted_at IS NULL", message.ConversationID).
+		Scan(&members).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation members: %v", err)
+	}
+
+	memberIDs := make(map[int]bool, len(members))
+	byHandle := make(map[string][]int, len(members))
+	for _, member := range members {
+		memberIDs[member.ID] = true
+		handle := mentionHandle(member.Email)
+		byHandle[handle] = append(byHandle[handle], member.ID)
+	}
+
+	var mentions []models.Mention
+	mentioned := make(map[int]bool)
+	for _, handle := range handles {
+		userID, err := strconv.Atoi(handle)
+		if err != nil || !memberIDs[userID] {
+			if ids := byHandle[handle]; len(ids) == 1 {
+				userID = ids[0]
+			} else {
+				continue
+			}
+		}
+
+		if userID == message.SenderID || mentioned[userID] {
+			continue
+		}
+		mentioned[userID] = true
+
+		mentions = append(mentions, models.Mention{
+			MessageID:      message.ID,
+			UserID:         userID,
+			ConversationID: message.ConversationID,
+			SenderID:       message.SenderID,
+			CreatedAt:      message.CreatedAt,
+		})
+	}
+
+	if len(mentions) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&mentions).Error; err != nil {
+		return nil, fmt.Errorf("failed to save mentions: %v", err)
+	}
+
+	return mentions, nil
+}
+
+// notifyMentions mengirim event mention berprioritas tinggi ke setiap user
+// yang disebut, selain event message biasa yang sudah dikirim
+func (cs *ChatServiceServer) notifyMentions(ctx context.Context, message models.Message, mentions []models.Mention) {
+	var userIDs []int
+	for _, mention := range mentions {
+		if mention.MessageID == message.ID {
+			userIDs = append(userIDs, mention.UserID)
+		}
+	}
+
+	event := messageEvent(message)
+	event.Type = EventMention
+	event.Priority = PriorityHigh
+	cs.deliverAll(ctx, userIDs, event)
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	var many []string
+	for i := 0; i < maxMentionsPerMessage+5; i++ {
+		many = append(many, fmt.Sprintf("@user%d", i))
+	}
+
+	var firstMax []string
+	for i := 0; i < maxMentionsPerMessage; i++ {
+		firstMax = append(firstMax, fmt.Sprintf("user%d", i))
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{name: "no mention", content: "halo semua"},
+		{name: "handle", content: "@budi tolong cek", want: []string{"budi"}},
+		{name: "user id", content: "cc @42", want: []string{"42"}},
+		{name: "lowercased and deduplicated", content: "@Budi @budi @BUDI", want: []string{"budi"}},
+		{name: "several handles keep order", content: "@siti lalu @budi, @andi!", want: []string{"siti", "budi", "andi"}},
+		{name: "trailing punctuation trimmed", content: "thanks @budi. dan @andi-", want: []string{"budi", "andi"}},
+		{name: "handle with dot and dash", content: "@budi.santoso @a-b", want: []string{"budi.santoso", "a-b"}},
+		{name: "email is not a mention", content: "kirim ke budi@example.com", want: nil},
+		{name: "double at is not a mention", content: "@@budi", want: nil},
+		{name: "lone at", content: "ketemu @ kantor", want: nil},
+		{name: "limited per message", content: strings.Join(many, " "), want: firstMax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMentions(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMentions(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentionHandle(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{email: "budi@example.com", want: "budi"},
+		{email: "Budi.Santoso@example.com", want: "budi.santoso"},
+		{email: "tanpa-domain", want: "tanpa-domain"},
+	}
+
+	for _, tt := range tests {
+		if got := mentionHandle(tt.email); got != tt.want {
+			t.Errorf("mentionHandle(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
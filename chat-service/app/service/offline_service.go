@@ -0,0 +1,133 @@
+package service
+
+import (
+	"chat-service/app/models"
+	pb "chat-service/proto/script"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	replayBatchSize = 100
+
+	// EventResync memberi tahu client bahwa stream sempat tertinggal dan
+	// event selain pesan perlu diambil lewat Sync sejak seq terakhirnya
+	EventResync = "resync"
+)
+
+// replayOffline mengirim ulang dari Postgres semua pesan setelah cursor device
+// atau pesan terakhir yang sudah dikirim ke stream, mana yang lebih baru.
+// Device baru (atau koneksi tanpa device_id) menerima pesan yang belum dibaca.
+// Pesan yang dikirim dicatat di replayed agar event live yang sama dilewati.
+func (cs *ChatServiceServer) replayOffline(sub *subscriber, push func(*pb.StreamMessagesResponse) error) error {
+	lastID, known, err := cs.deviceCursor(sub)
+	if err != nil {
+		return err
+	}
+	lastID = max(lastID, sub.lastPushed)
+	clear(sub.replayed)
+
+	for {
+		query := cs.db.Model(&models.Message{}).
+			Joins("JOIN conversation_members AS cm ON cm.conversation_id = messages.conversation_id AND cm.user_id = ?", sub.userID).
+			Where("messages.sender_id <> ? AND messages.id > ?", sub.userID, lastID).
+			Scopes(notExpired)
+		if !known {
+			query = query.Where("messages.id > cm.last_read_message_id")
+		}
+
+		var messages []models.Message
+		if err := query.Preload("Attachments").Order("messages.id ASC").Limit(replayBatchSize).Find(&messages).Error; err != nil {
+			return err
+		}
+
+		for _, message := range messages {
+			if err := push(messageEvent(message)); err != nil {
+				return err
+			}
+			sub.replayed[int32(message.ID)] = true
+			lastID = message.ID
+		}
+
+		if len(messages) < replayBatchSize {
+			return nil
+		}
+	}
+}
+
+// catchUp dipanggil loop stream setelah subscriber ditandai tertinggal.
+// Event yang masih ada di buffer dikirim lebih dulu, lalu pesan yang
+// terlewat diputar ulang dari Postgres dan client diminta melakukan Sync.
+func (cs *ChatServiceServer) catchUp(sub *subscriber, push func(*pb.StreamMessagesResponse) error) error {
+	for drained := false; !drained; {
+		select {
+		case msg := <-sub.ch:
+			if sub.alreadyReplayed(msg) {
+				continue
+			}
+			if err := push(msg); err != nil {
+				return err
+			}
+		default:
+			drained = true
+		}
+	}
+
+	// Event baru setelah titik ini kembali masuk buffer; yang juga ikut
+	// terambil oleh replay akan dilewati lewat alreadyReplayed
+	sub.lagging.Store(false)
+
+	if err := cs.replayOffline(sub, push); err != nil {
+		return err
+	}
+
+	deliveryStats.Add("resynced", 1)
+	return push(&pb.StreamMessagesResponse{Type: EventResync, Timestamp: time.Now().Format(time.RFC3339)})
+}
+
+// deviceCursor mengembalikan id pesan terakhir yang di-ack device
+// dan apakah device tersebut sudah pernah tercatat
+func (cs *ChatServiceServer) deviceCursor(sub *subscriber) (int, bool, error) {
+	if sub.deviceID == "" {
+		return 0, false, nil
+	}
+
+	var cursor models.DeviceCursor
+	err := cs.db.Where("user_id = ? AND device_id = ?", sub.userID, sub.deviceID).First(&cursor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+
+	return cursor.LastAckedMessageID, true, nil
+}
+
+// advanceCursor memajukan cursor device, tidak pernah mundur
+func (cs *ChatServiceServer) advanceCursor(sub *subscriber, messageID int) {
+	if sub.deviceID == "" {
+		return
+	}
+
+	cursor := models.DeviceCursor{
+		UserID:             sub.userID,
+		DeviceID:           sub.deviceID,
+		LastAckedMessageID: messageID,
+		UpdatedAt:          time.Now(),
+	}
+
+	err := cs.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "device_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"last_acked_message_id": gorm.Expr("GREATEST(device_cursors.last_acked_message_id, EXCLUDED.last_acked_message_id)"),
+			"updated_at":            cursor.UpdatedAt,
+		}),
+	}).Create(&cursor).Error
+	if err != nil {
+		log.Printf("Error advancing cursor of user %d device %s: %v", sub.userID, sub.deviceID, err)
+	}
+}
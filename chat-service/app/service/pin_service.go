@@ -0,0 +1,192 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	EventPin = "pin"
+
+	maxPinsPerConversation = 50
+)
+
+func (cs *ChatServiceServer) PinMessage(ctx context.Context, req *pb.PinRequest) (*pb.PinResponse, error) {
+	return cs.pin(ctx, req, true)
+}
+
+func (cs *ChatServiceServer) UnpinMessage(ctx context.Context, req *pb.PinRequest) (*pb.PinResponse, error) {
+	return cs.pin(ctx, req, false)
+}
+
+func (cs *ChatServiceServer) pin(ctx context.Context, req *pb.PinRequest, pinned bool) (*pb.PinResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	var message models.Message
+	err = cs.db.Scopes(notExpired).First(&message, req.MessageId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("message not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get message: %v", err)
+	}
+
+	if err := cs.checkCanPin(message.ConversationID, *userID); err != nil {
+		return nil, err
+	}
+
+	var changed int64
+	if pinned {
+		var count int64
+		err = cs.db.Model(&models.PinnedMessage{}).Where("conversation_id = ?", message.ConversationID).Count(&count).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to count pinned messages: %v", err)
+		}
+		if count >= maxPinsPerConversation {
+			return nil, fmt.Errorf("a conversation can have at most %d pinned messages", maxPinsPerConversation)
+		}
+
+		result := cs.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.PinnedMessage{
+			ConversationID: message.ConversationID,
+			MessageID:      message.ID,
+			PinnedBy:       *userID,
+		})
+		err, changed = result.Error, result.RowsAffected
+	} else {
+		result := cs.db.Where("message_id = ?", message.ID).Delete(&models.PinnedMessage{})
+		err, changed = result.Error, result.RowsAffected
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update pin: %v", err)
+	}
+
+	if changed > 0 {
+		now := time.Now().Format(time.RFC3339)
+		cs.notifyParticipants(ctx, message.ConversationID, 0, &pb.StreamMessagesResponse{
+			Type:           EventPin,
+			MessageId:      int32(message.ID),
+			ConversationId: int32(message.ConversationID),
+			SenderId:       int32(*userID),
+			GroupId:        int32(message.GroupId),
+			Timestamp:      now,
+			Pin: &pb.Pin{
+				MessageId:      int32(message.ID),
+				ConversationId: int32(message.ConversationID),
+				UserId:         int32(*userID),
+				Pinned:         pinned,
+				Timestamp:      now,
+			},
+		})
+	}
+
+	pins, err := cs.pinnedMessages(*userID, message.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := "Message pinned"
+	if !pinned {
+		status = "Message unpinned"
+	}
+
+	return &pb.PinResponse{Status: status, Pins: pins}, nil
+}
+
+func (cs *ChatServiceServer) ListPinned(ctx context.Context, req *pb.ListPinnedRequest) (*pb.ListPinnedResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	if req.ConversationId == 0 {
+		return nil, fmt.Errorf("conversation_id is required")
+	}
+
+	if err := cs.checkConversationMember(int(req.ConversationId), *userID); err != nil {
+		return nil, err
+	}
+
+	pins, err := cs.pinnedMessages(*userID, int(req.ConversationId))
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ListPinnedResponse{Pins: pins}, nil
+}
+
+// checkCanPin: di percakapan 1:1 semua peserta boleh menyematkan pesan,
+// di group hanya admin
+func (cs *ChatServiceServer) checkCanPin(conversationID, userID int) error {
+	var conversation models.Conversation
+	if err := cs.db.First(&conversation, conversationID).Error; err != nil {
+		return fmt.Errorf("failed to get conversation: %v", err)
+	}
+
+	if conversation.GroupID != nil {
+		return cs.checkGroupAdmin(*conversation.GroupID, userID)
+	}
+
+	return cs.checkConversationMember(conversationID, userID)
+}
+
+// pinnedMessages mengambil pesan yang disematkan, terbaru lebih dulu.
+// Pesan yang sudah dihapus atau kedaluwarsa tidak ditampilkan.
+func (cs *ChatServiceServer) pinnedMessages(viewerID, conversationID int) ([]*pb.PinnedMessage, error) {
+	var pins []models.PinnedMessage
+	err := cs.db.Where("conversation_id = ?", conversationID).Order("created_at DESC, id DESC").Find(&pins).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pinned messages: %v", err)
+	}
+
+	result := []*pb.PinnedMessage{}
+	if len(pins) == 0 {
+		return result, nil
+	}
+
+	messageIDs := make([]int, len(pins))
+	for i, pin := range pins {
+		messageIDs[i] = pin.MessageID
+	}
+
+	var messages []models.Message
+	err = cs.db.Scopes(notExpired).Where("id IN ?", messageIDs).Find(&messages).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pinned messages: %v", err)
+	}
+
+	decorated, err := cs.chatMessages(viewerID, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int32]*pb.ChatMessage, len(decorated))
+	for _, message := range decorated {
+		byID[message.Id] = message
+	}
+
+	for _, pin := range pins {
+		message, ok := byID[int32(pin.MessageID)]
+		if !ok {
+			continue
+		}
+		result = append(result, &pb.PinnedMessage{
+			Message:  message,
+			PinnedBy: int32(pin.PinnedBy),
+			PinnedAt: pin.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	EventReaction = "reaction"
+
+	maxEmojiLength = 32
+)
+
+func (cs *ChatServiceServer) AddReaction(ctx context.Context, req *pb.ReactionRequest) (*pb.ReactionResponse, error) {
+	return cs.react(ctx, req, true)
+}
+
+func (cs *ChatServiceServer) RemoveReaction(ctx context.Context, req *pb.ReactionRequest) (*pb.ReactionResponse, error) {
+	return cs.react(ctx, req, false)
+}
+
+func (cs *ChatServiceServer) react(ctx context.Context, req *pb.ReactionRequest, add bool) (*pb.ReactionResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	emoji := strings.TrimSpace(req.Emoji)
+	if emoji == "" {
+		return nil, fmt.Errorf("emoji is required")
+	}
+	if len(emoji) > maxEmojiLength {
+		return nil, fmt.Errorf("emoji is too long")
+	}
+
+	var message models.Message
+	err = cs.db.Scopes(notExpired).First(&message, req.MessageId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("message not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get message: %v", err)
+	}
+
+	if err := cs.checkConversationMember(message.ConversationID, *userID); err != nil {
+		return nil, err
+	}
+
+	var changed int64
+	if add {
+		result := cs.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.MessageReaction{
+			MessageID: message.ID,
+			UserID:    *userID,
+			Emoji:     emoji,
+		})
+		err, changed = result.Error, result.RowsAffected
+	} else {
+		result := cs.db.Where("message_id = ? AND user_id = ? AND emoji = ?", message.ID, *userID, emoji).
+			Delete(&models.MessageReaction{})
+		err, changed = result.Error, result.RowsAffected
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update reaction: %v", err)
+	}
+
+	reactions, err := cs.reactionCounts(*userID, []int{message.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	// Reaksi yang sama dua kali tidak perlu dikirim ulang ke peserta lain
+	if changed > 0 {
+		cs.notifyParticipants(ctx, message.ConversationID, *userID, &pb.StreamMessagesResponse{
+			Type:           EventReaction,
+			MessageId:      int32(message.ID),
+			ConversationId: int32(message.ConversationID),
+			SenderId:       int32(*userID),
+			GroupId:        int32(message.GroupId),
+			Timestamp:      time.Now().Format(time.RFC3339),
+			Reaction: &pb.Reaction{
+				MessageId:      int32(message.ID),
+				ConversationId: int32(message.ConversationID),
+				UserId:         int32(*userID),
+				Emoji:          emoji,
+				Added:          add,
+				Reactions:      withoutReacted(reactions[message.ID]),
+			},
+		})
+	}
+
+	status := "Reaction added"
+	if !add {
+		status = "Reaction removed"
+	}
+
+	return &pb.ReactionResponse{Status: status, Reactions: reactions[message.ID]}, nil
+}
+
+// reactionCounts menghitung reaksi per emoji untuk setiap pesan,
+// urut dari emoji yang pertama kali diberikan
+func (cs *ChatServiceServer) reactionCounts(viewerID int, messageIDs []int) (map[int][]*pb.ReactionCount, error) {
+	result := make(map[int][]*pb.ReactionCount)
+	if len(messageIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		MessageID int
+		Emoji     string
+		Count     int
+		Reacted   bool
+	}
+	err := cs.db.Model(&models.MessageReaction{}).
+		Select("message_id, emoji, COUNT(*) AS count, BOOL_OR(user_id = ?) AS reacted", viewerID).
+		Where("message_id IN ?", messageIDs).
+		Group("message_id, emoji").
+		Order("MIN(id) ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reactions: %v", err)
+	}
+
+	for _, row := range rows {
+		result[row.MessageID] = append(result[row.MessageID], &pb.ReactionCount{
+			Emoji:   row.Emoji,
+			Count:   int32(row.Count),
+			Reacted: row.Reacted,
+		})
+	}
+
+	return result, nil
+}
+
+// withoutReacted menghapus penanda reacted karena event dikirim ke user lain
+func withoutReacted(reactions []*pb.ReactionCount) []*pb.ReactionCount {
+	result := make([]*pb.ReactionCount, 0, len(reactions))
+	for _, r := range reactions {
+		result = append(result, &pb.ReactionCount{Emoji: r.Emoji, Count: r.Count})
+	}
+	return result
+}
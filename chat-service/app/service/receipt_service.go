@@ -0,0 +1,133 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	EventMessage = "message"
+	EventReceipt = "receipt"
+)
+
+func (cs *ChatServiceServer) MarkRead(ctx context.Context, req *pb.MarkReadRequest) (*pb.MarkReadResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	if req.ConversationId == 0 || req.UpToMessageId == 0 {
+		return nil, fmt.Errorf("conversation_id and up_to_message_id are required")
+	}
+
+	var member models.ConversationMember
+	err = cs.db.Where("conversation_id = ? AND user_id = ?", req.ConversationId, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("you are not a participant of this conversation")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get conversation member: %v", err)
+	}
+
+	now := time.Now()
+	var updated []struct {
+		MessageID int
+		SenderID  int
+	}
+
+	err = cs.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&member).
+			Update("last_read_message_id", gorm.Expr("GREATEST(last_read_message_id, ?)", req.UpToMessageId)).Error
+		if err != nil {
+			return fmt.Errorf("failed to update conversation member: %v", err)
+		}
+
+		return tx.Raw(`UPDATE message_receipts AS r
+			SET status = ?, read_at = ?, delivered_at = COALESCE(r.delivered_at, ?), updated_at = ?
+			FROM messages AS m
+			WHERE m.id = r.message_id AND m.conversation_id = ? AND m.id <= ?
+			AND r.user_id = ? AND r.status <> ?
+			RETURNING r.message_id, m.sender_id`,
+			models.ReceiptRead, now, now, now, req.ConversationId, req.UpToMessageId, userID, models.ReceiptRead).
+			Scan(&updated).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark messages as read: %v", err)
+	}
+
+	// Read receipt bersifat kumulatif: cukup kirim id pesan terbaru per pengirim
+	latest := make(map[int]int)
+	for _, u := range updated {
+		if u.MessageID > latest[u.SenderID] {
+			latest[u.SenderID] = u.MessageID
+		}
+	}
+
+	for senderID, messageID := range latest {
+		cs.deliver(ctx, senderID, receiptEvent(messageID, int(req.ConversationId), *userID, models.ReceiptRead, now))
+	}
+
+	return &pb.MarkReadResponse{Status: "Messages marked as read", Updated: int32(len(updated))}, nil
+}
+
+// markDelivered menandai pesan sudah sampai ke stream penerima
+// dan memberi tahu pengirim
+func (cs *ChatServiceServer) markDelivered(userID int, msg *pb.StreamMessagesResponse) {
+	now := time.Now()
+	result := cs.db.Model(&models.MessageReceipt{}).
+		Where("message_id = ? AND user_id = ? AND status = ?", msg.MessageId, userID, models.ReceiptSent).
+		Updates(map[string]interface{}{
+			"status":       models.ReceiptDelivered,
+			"delivered_at": now,
+		})
+	if result.Error != nil {
+		log.Printf("Error marking message %d as delivered: %v", msg.MessageId, result.Error)
+		return
+	}
+
+	if result.RowsAffected > 0 {
+		cs.deliver(context.Background(), int(msg.SenderId),
+			receiptEvent(int(msg.MessageId), int(msg.ConversationId), userID, models.ReceiptDelivered, now))
+	}
+}
+
+func createReceipts(tx *gorm.DB, messageID int, userIDs []int) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	receipts := make([]models.MessageReceipt, 0, len(userIDs))
+	for _, id := range userIDs {
+		receipts = append(receipts, models.MessageReceipt{MessageID: messageID, UserID: id, Status: models.ReceiptSent})
+	}
+
+	if err := tx.Create(&receipts).Error; err != nil {
+		return fmt.Errorf("failed to save message receipts: %v", err)
+	}
+
+	return nil
+}
+
+func receiptEvent(messageID, conversationID, userID int, status string, at time.Time) *pb.StreamMessagesResponse {
+	return &pb.StreamMessagesResponse{
+		Type:           EventReceipt,
+		MessageId:      int32(messageID),
+		ConversationId: int32(conversationID),
+		Timestamp:      at.Format(time.RFC3339),
+		Receipt: &pb.Receipt{
+			MessageId:      int32(messageID),
+			ConversationId: int32(conversationID),
+			UserId:         int32(userID),
+			Status:         status,
+			Timestamp:      at.Format(time.RFC3339),
+		},
+	}
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	EventMessageExpired = "message_expired"
+
+	sweepInterval  = 30 * time.Second
+	sweepBatchSize = 200
+
+	// Lampiran yang tidak pernah dipakai di pesan dihapus setelah ini
+	unattachedUploadTTL = 24 * time.Hour
+)
+
+// allowedRetentions adalah pilihan timer pesan sementara dalam detik
+var allowedRetentions = map[int32]bool{
+	0:      true,
+	3600:   true, // 1 jam
+	86400:  true, // 24 jam
+	604800: true, // 7 hari
+}
+
+func (cs *ChatServiceServer) SetConversationRetention(ctx context.Context, req *pb.SetConversationRetentionRequest) (*pb.ConversationRetention, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	if !allowedRetentions[req.RetentionSeconds] {
+		return nil, fmt.Errorf("retention_seconds must be 0, 3600, 86400 or 604800")
+	}
+
+	conversation, err := cs.findConversation(*userID, req.ConversationId, req.PeerId, req.GroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	if conversation == nil {
+		if err := cs.checkUsersExist([]int{int(req.PeerId)}); err != nil {
+			return nil, err
+		}
+		conversation, err = directConversation(cs.db, *userID, int(req.PeerId))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Di group hanya admin yang boleh mengubah timer
+	if conversation.GroupID != nil {
+		if err := cs.checkGroupAdmin(*conversation.GroupID, *userID); err != nil {
+			return nil, err
+		}
+	}
+
+	err = cs.db.Model(&models.Conversation{}).
+		Where("id = ?", conversation.ID).
+		Update("retention", req.RetentionSeconds).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to update conversation retention: %v", err)
+	}
+
+	return &pb.ConversationRetention{
+		ConversationId:   int32(conversation.ID),
+		RetentionSeconds: req.RetentionSeconds,
+	}, nil
+}
+
+// applyRetention mengisi waktu kedaluwarsa pesan dari timer percakapan
+func applyRetention(message *models.Message, conversation *models.Conversation) {
+	if conversation.Retention <= 0 {
+		return
+	}
+
+	expiresAt := message.CreatedAt.Add(time.Duration(conversation.Retention) * time.Second)
+	message.ExpiresAt = &expiresAt
+}
+
+// notExpired membatasi query tabel messages pada pesan yang belum
+// kedaluwarsa, walaupun sweeper belum menghapusnya. Dipakai lewat Scopes.
+func notExpired(db *gorm.DB) *gorm.DB {
+	return db.Where("(messages.expires_at IS NULL OR messages.expires_at > NOW())")
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// RunRetentionSweeper menghapus permanen pesan yang sudah kedaluwarsa,
+// lampiran yang tidak pernah dipakai, dan memangkas log event Sync yang
+// melewati EVENT_RETENTION_DAYS. Aman
+// dijalankan di beberapa replica karena pesan diklaim dengan FOR UPDATE
+// SKIP LOCKED di dalam transaksi penghapusan.
+func (cs *ChatServiceServer) RunRetentionSweeper(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		for cs.sweepExpired(ctx) == sweepBatchSize {
+		}
+
+		for cs.sweepUnattached() == sweepBatchSize {
+		}
+
+		for {
+			pruned, err := cs.pruneUserEvents()
+			if err != nil {
+				log.Printf("Error pruning user events: %v", err)
+			}
+			if pruned < sweepBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweepExpired menghapus satu batch pesan kedaluwarsa beserta receipt,
+// reaction, riwayat edit, pin, mention, salinan di log event user dan
+// lampirannya, lalu memberi tahu peserta percakapan. Mengembalikan jumlah
+// pesan terhapus.
+func (cs *ChatServiceServer) sweepExpired(ctx context.Context) int {
+	var expired []models.Message
+	var attachments []models.Attachment
+
+	err := cs.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Raw(`DELETE FROM messages WHERE id IN (
+				SELECT id FROM messages
+				WHERE expires_at IS NOT NULL AND expires_at <= NOW()
+				ORDER BY expires_at ASC LIMIT ?
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING *`, sweepBatchSize).
+			Scan(&expired).Error
+		if err != nil {
+			return fmt.Errorf("failed to delete expired messages: %v", err)
+		}
+		if len(expired) == 0 {
+			return nil
+		}
+
+		messageIDs := make([]int, len(expired))
+		conversationIDs := []int{}
+		for i, message := range expired {
+			messageIDs[i] = message.ID
+			if !containsID(conversationIDs, message.ConversationID) {
+				conversationIDs = append(conversationIDs, message.ConversationID)
+			}
+		}
+
+		dependents := []interface{}{
+			&models.MessageReceipt{},
+			&models.MessageReaction{},
+			&models.MessageEdit{},
+			&models.UserEvent{},
+			&models.PinnedMessage{},
+			&models.Mention{},
+		}
+		for _, model := range dependents {
+			if err := tx.Unscoped().Where("message_id IN ?", messageIDs).Delete(model).Error; err != nil {
+				return fmt.Errorf("failed to delete expired message data: %v", err)
+			}
+		}
+
+		err = tx.Raw(`DELETE FROM attachments WHERE message_id IN ? RETURNING *`, messageIDs).
+			Scan(&attachments).Error
+		if err != nil {
+			return fmt.Errorf("failed to delete expired attachments: %v", err)
+		}
+
+		for _, conversationID := range conversationIDs {
+			if err := refreshLastMessage(tx, conversationID); err != nil {
+				return fmt.Errorf("failed to update conversation: %v", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error sweeping expired messages: %v", err)
+		return 0
+	}
+
+	cs.deleteUnreferencedBlobs(attachments)
+
+	now := time.Now().Format(time.RFC3339)
+	for _, message := range expired {
+		cs.notifyParticipants(ctx, message.ConversationID, 0, &pb.StreamMessagesResponse{
+			Type:           EventMessageExpired,
+			MessageId:      int32(message.ID),
+			ConversationId: int32(message.ConversationID),
+			SenderId:       int32(message.SenderID),
+			GroupId:        int32(message.GroupId),
+			Timestamp:      now,
+		})
+	}
+
+	return len(expired)
+}
+
+// sweepUnattached menghapus satu batch lampiran yang diunggah lebih dari
+// unattachedUploadTTL lalu tidak pernah ditautkan ke pesan. Lampiran milik
+// user yang masih punya pesan terjadwal dilewati karena pesan terjadwal baru
+// menautkan lampirannya saat dikirim. Mengembalikan jumlah lampiran terhapus.
+func (cs *ChatServiceServer) sweepUnattached() int {
+	var attachments []models.Attachment
+	err := cs.db.Raw(`DELETE FROM attachments WHERE id IN (
+			SELECT id FROM attachments
+			WHERE message_id = 0 AND created_at < ?
+				AND uploader_id NOT IN (
+					SELECT sender_id FROM scheduled_messages WHERE status IN ?
+				)
+			ORDER BY id LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		time.Now().Add(-unattachedUploadTTL),
+		[]string{models.ScheduledPending, models.ScheduledSending},
+		sweepBatchSize).
+		Scan(&attachments).Error
+	if err != nil {
+		log.Printf("Error sweeping unattached uploads: %v", err)
+		return 0
+	}
+
+	cs.deleteUnreferencedBlobs(attachments)
+	return len(attachments)
+}
+
+// deleteUnreferencedBlobs menghapus blob lampiran yang sudah tidak dipakai
+// lampiran lain (salinan untuk pesan ke beberapa penerima memakai blob yang sama)
+func (cs *ChatServiceServer) deleteUnreferencedBlobs(attachments []models.Attachment) {
+	checked := make(map[string]bool)
+	for _, attachment := range attachments {
+		for column, key := range map[string]string{
+			"storage_key":   attachment.StorageKey,
+			"thumbnail_key": attachment.ThumbnailKey,
+		} {
+			if key == "" || checked[key] {
+				continue
+			}
+			checked[key] = true
+
+			var count int64
+			err := cs.db.Model(&models.Attachment{}).Where(column+" = ?", key).Count(&count).Error
+			if err != nil {
+				log.Printf("Error checking blob %s: %v", key, err)
+				continue
+			}
+			if count == 0 {
+				cs.deleteBlob(key)
+			}
+		}
+	}
+}
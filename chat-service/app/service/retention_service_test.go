@@ -0,0 +1,76 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"testing"
+	"time"
+)
+
+func TestAllowedRetentions(t *testing.T) {
+	tests := []struct {
+		seconds int32
+		want    bool
+	}{
+		{seconds: 0, want: true},
+		{seconds: 3600, want: true},
+		{seconds: 86400, want: true},
+		{seconds: 604800, want: true},
+		{seconds: -1},
+		{seconds: 1},
+		{seconds: 60},
+		{seconds: 7200},
+		{seconds: 2592000},
+	}
+
+	for _, tt := range tests {
+		if got := allowedRetentions[tt.seconds]; got != tt.want {
+			t.Errorf("allowedRetentions[%d] = %v, want %v", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		retention int
+		want      *time.Time
+	}{
+		{name: "retention disabled", retention: 0},
+		{name: "negative retention is ignored", retention: -60},
+		{name: "one hour", retention: 3600, want: ptrTime(createdAt.Add(time.Hour))},
+		{name: "seven days", retention: 604800, want: ptrTime(createdAt.Add(7 * 24 * time.Hour))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := models.Message{CreatedAt: createdAt}
+			applyRetention(&message, &models.Conversation{Retention: tt.retention})
+
+			switch {
+			case tt.want == nil && message.ExpiresAt != nil:
+				t.Errorf("expires_at = %v, want none", *message.ExpiresAt)
+			case tt.want != nil && message.ExpiresAt == nil:
+				t.Errorf("expires_at is not set, want %v", *tt.want)
+			case tt.want != nil && !message.ExpiresAt.Equal(*tt.want):
+				t.Errorf("expires_at = %v, want %v", *message.ExpiresAt, *tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatOptionalTime(t *testing.T) {
+	if got := formatOptionalTime(nil); got != "" {
+		t.Errorf("formatOptionalTime(nil) = %q, want empty", got)
+	}
+
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	if got := formatOptionalTime(&at); got != "2024-05-01T10:00:00Z" {
+		t.Errorf("formatOptionalTime = %q, want 2024-05-01T10:00:00Z", got)
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+)
+
+const (
+	schedulerPollInterval = 5 * time.Second
+	// Jadwal yang berstatus sending lebih lama dari ini dianggap ditinggal
+	// replica yang mati dan akan dikirim ulang
+	scheduledStaleAfter = 2 * time.Minute
+	maxScheduleAhead    = 365 * 24 * time.Hour
+)
+
+func (cs *ChatServiceServer) ScheduleMessage(ctx context.Context, req *pb.ScheduleMessageRequest) (*pb.ScheduledMessage, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	msg := req.Message
+	if msg == nil {
+		return nil, fmt.Errorf("message is required")
+	}
+
+	sendAt, err := time.Parse(time.RFC3339, req.SendAt)
+	if err != nil {
+		return nil, fmt.Errorf("send_at must be an RFC3339 timestamp")
+	}
+	if !sendAt.After(time.Now()) {
+		return nil, fmt.Errorf("send_at must be in the future")
+	}
+	if sendAt.After(time.Now().Add(maxScheduleAhead)) {
+		return nil, fmt.Errorf("send_at must be within %d days", int(maxScheduleAhead.Hours()/24))
+	}
+
+	if msg.Content == "" && len(msg.AttachmentIds) == 0 {
+		return nil, fmt.Errorf("content or attachment_ids is required")
+	}
+
+	if msg.GroupId != 0 {
+		memberIDs, err := cs.groupMemberIDs(int(msg.GroupId))
+		if err != nil {
+			return nil, err
+		}
+		if !containsID(memberIDs, *userID) {
+			return nil, fmt.Errorf("you are not a member of this group")
+		}
+	} else {
+		receiverIDs := uniqueIDs(msg.ReceiverId, 0)
+		if len(receiverIDs) == 0 {
+			return nil, fmt.Errorf("receiver ID is required")
+		}
+		if err := cs.checkUsersExist(receiverIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	// Pengirim dan client_message_id diisi scheduler saat pesan dikirim
+	msg = proto.Clone(msg).(*pb.SendMessageRequest)
+	msg.SenderId = 0
+	msg.ClientMessageId = ""
+
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %v", err)
+	}
+
+	scheduled := models.ScheduledMessage{
+		SenderID: *userID,
+		Request:  payload,
+		SendAt:   sendAt,
+		Status:   models.ScheduledPending,
+	}
+	if err := cs.db.Create(&scheduled).Error; err != nil {
+		return nil, fmt.Errorf("failed to schedule message: %v", err)
+	}
+
+	return scheduledProto(scheduled, nil)
+}
+
+func (cs *ChatServiceServer) ListScheduledMessages(ctx context.Context, req *pb.ListScheduledMessagesRequest) (*pb.ListScheduledMessagesResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	query := cs.db.Where("sender_id = ?", userID)
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	var rows []models.ScheduledMessage
+	if err := query.Order("send_at ASC, id ASC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed get list scheduled message: %v", err)
+	}
+
+	messageIDs, err := cs.scheduledMessageIDs(*userID, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.ListScheduledMessagesResponse{ScheduledMessages: make([]*pb.ScheduledMessage, 0, len(rows))}
+	for _, row := range rows {
+		scheduled, err := scheduledProto(row, messageIDs[scheduledClientMessageID(row.ID)])
+		if err != nil {
+			return nil, err
+		}
+		res.ScheduledMessages = append(res.ScheduledMessages, scheduled)
+	}
+
+	return res, nil
+}
+
+func (cs *ChatServiceServer) CancelScheduledMessage(ctx context.Context, req *pb.CancelScheduledMessageRequest) (*pb.ScheduledMessage, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	// Hanya jadwal yang belum diklaim scheduler yang bisa dibatalkan
+	result := cs.db.Model(&models.ScheduledMessage{}).
+		Where("id = ? AND sender_id = ? AND status = ?", req.Id, userID, models.ScheduledPending).
+		Updates(map[string]interface{}{
+			"status":     models.ScheduledCancelled,
+			"updated_at": gorm.Expr("NOW()"),
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to cancel scheduled message: %v", result.Error)
+	}
+
+	var scheduled models.ScheduledMessage
+	err = cs.db.Where("id = ? AND sender_id = ?", req.Id, userID).First(&scheduled).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("scheduled message not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled message: %v", err)
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("scheduled message is already %s", scheduled.Status)
+	}
+
+	return scheduledProto(scheduled, nil)
+}
+
+// RunScheduler mengirim pesan terjadwal yang sudah jatuh tempo. Aman
+// dijalankan di beberapa replica: setiap jadwal diklaim dengan FOR UPDATE
+// SKIP LOCKED, dan pesan dikirim dengan client_message_id milik jadwal
+// sehingga jadwal yang diklaim ulang setelah replica mati tidak membuat
+// pesan ganda.
+func (cs *ChatServiceServer) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for cs.sendNextScheduled() {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendNextScheduled mengklaim satu jadwal yang jatuh tempo dan mengirimnya,
+// mengembalikan false jika tidak ada jadwal yang menunggu
+func (cs *ChatServiceServer) sendNextScheduled() bool {
+	var scheduled models.ScheduledMessage
+	err := cs.db.Raw(`UPDATE scheduled_messages SET status = ?, updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM scheduled_messages
+			WHERE (status = ? AND send_at <= NOW()) OR (status = ? AND updated_at < NOW() - ? * INTERVAL '1 second')
+			ORDER BY send_at ASC LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		models.ScheduledSending, models.ScheduledPending, models.ScheduledSending,
+		int(scheduledStaleAfter/time.Second)).
+		Scan(&scheduled).Error
+	if err != nil {
+		log.Printf("Error claiming scheduled message: %v", err)
+		return false
+	}
+	if scheduled.ID == 0 {
+		return false
+	}
+
+	// Semua waktu jadwal memakai jam database, sama dengan query klaim
+	updates := map[string]interface{}{"updated_at": gorm.Expr("NOW()")}
+
+	var req pb.SendMessageRequest
+	err = proto.Unmarshal(scheduled.Request, &req)
+	if err == nil {
+		req.ClientMessageId = scheduledClientMessageID(scheduled.ID)
+		_, err = cs.sendMessage(context.Background(), scheduled.SenderID, &req)
+	}
+
+	if err != nil {
+		log.Printf("Error sending scheduled message %d: %v", scheduled.ID, err)
+		updates["status"] = models.ScheduledFailed
+		updates["error"] = err.Error()
+	} else {
+		updates["status"] = models.ScheduledSent
+		updates["sent_at"] = gorm.Expr("NOW()")
+	}
+
+	err = cs.db.Model(&models.ScheduledMessage{}).
+		Where("id = ? AND status = ?", scheduled.ID, models.ScheduledSending).
+		Updates(updates).Error
+	if err != nil {
+		log.Printf("Error saving scheduled message %d: %v", scheduled.ID, err)
+	}
+
+	return true
+}
+
+func scheduledClientMessageID(id int) string {
+	return fmt.Sprintf("%s%d", scheduledClientMessagePrefix, id)
+}
+
+// scheduledMessageIDs mengambil id pesan yang sudah terkirim per client_message_id jadwal
+func (cs *ChatServiceServer) scheduledMessageIDs(senderID int, rows []models.ScheduledMessage) (map[string][]int32, error) {
+	result := make(map[string][]int32)
+
+	var keys []string
+	for _, row := range rows {
+		if row.Status == models.ScheduledSent {
+			keys = append(keys, scheduledClientMessageID(row.ID))
+		}
+	}
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	var messages []struct {
+		ID              int
+		ClientMessageID string
+	}
+	err := cs.db.Model(&models.Message{}).
+		Select("id, client_message_id").
+		Where("sender_id = ? AND split_part(client_message_id, ?, 1) IN ?", senderID, clientMessageKeySeparator, keys).
+		Order("id ASC").
+		Scan(&messages).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled messages: %v", err)
+	}
+
+	for _, m := range messages {
+		key := clientMessageIDOf(m.ClientMessageID)
+		result[key] = append(result[key], int32(m.ID))
+	}
+
+	return result, nil
+}
+
+func scheduledProto(scheduled models.ScheduledMessage, messageIDs []int32) (*pb.ScheduledMessage, error) {
+	var req pb.SendMessageRequest
+	if err := proto.Unmarshal(scheduled.Request, &req); err != nil {
+		return nil, fmt.Errorf("failed to decode scheduled message %d: %v", scheduled.ID, err)
+	}
+
+	var sentAt string
+	if scheduled.SentAt != nil {
+		sentAt = scheduled.SentAt.Format(time.RFC3339)
+	}
+
+	return &pb.ScheduledMessage{
+		Id:         int32(scheduled.ID),
+		Message:    &req,
+		SendAt:     scheduled.SendAt.Format(time.RFC3339),
+		Status:     scheduled.Status,
+		Error:      scheduled.Error,
+		CreatedAt:  scheduled.CreatedAt.Format(time.RFC3339),
+		SentAt:     sentAt,
+		MessageIds: messageIDs,
+	}, nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"chat-service/database"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	maxSearchQuery     = 200
+
+	// Penanda sementara dari ts_headline, diganti <mark> setelah snippet di-escape
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+type searchRow struct {
+	models.Message
+	Snippet string
+}
+
+func (cs *ChatServiceServer) SearchMessages(ctx context.Context, req *pb.SearchMessagesRequest) (*pb.SearchMessagesResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	q := strings.TrimSpace(req.Query)
+	if q == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if len(q) > maxSearchQuery {
+		return nil, fmt.Errorf("query is too long")
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	} else if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	tsQuery := fmt.Sprintf("websearch_to_tsquery('%s', ?)", database.SearchConfig)
+	headline := fmt.Sprintf("ts_headline('%s', m.content, %s, ?)", database.SearchConfig, tsQuery)
+	options := fmt.Sprintf("StartSel=%s, StopSel=%s, MaxFragments=2, MaxWords=20, MinWords=5", highlightStart, highlightStop)
+
+	// Hanya percakapan yang diikuti user yang ikut dicari
+	query := cs.db.Table("messages AS m").
+		Select("m.*, "+headline+" AS snippet", q, options).
+		Joins("JOIN conversation_members AS cm ON cm.conversation_id = m.conversation_id AND cm.user_id = ?", *userID).
+		Where(fmt.Sprintf("to_tsvector('%s', m.content) @@ %s", database.SearchConfig, tsQuery), q).
+		Where("m.deleted_at IS NULL AND (m.expires_at IS NULL OR m.expires_at > NOW())")
+
+	if req.ConversationId != 0 || req.PeerId != 0 || req.GroupId != 0 {
+		conversation, err := cs.findConversation(*userID, req.ConversationId, req.PeerId, req.GroupId)
+		if err != nil {
+			return nil, err
+		}
+		if conversation == nil {
+			return &pb.SearchMessagesResponse{Results: []*pb.SearchResult{}}, nil
+		}
+		query = query.Where("m.conversation_id = ?", conversation.ID)
+	}
+
+	if req.From != "" {
+		from, err := parseSearchDate(req.From, false)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("m.created_at >= ?", from)
+	}
+
+	if req.To != "" {
+		to, err := parseSearchDate(req.To, true)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("m.created_at < ?", to)
+	}
+
+	if req.HasAttachment {
+		query = query.Where("EXISTS (SELECT 1 FROM attachments AS a WHERE a.message_id = m.id)")
+	}
+
+	if req.Cursor != "" {
+		cursorID, err := parseSearchCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("m.id < ?", cursorID)
+	}
+
+	var rows []searchRow
+	if err := query.Order("m.id DESC").Limit(limit + 1).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to search messages: %v", err)
+	}
+
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	messages := make([]models.Message, len(rows))
+	for i, row := range rows {
+		messages[i] = row.Message
+	}
+
+	chatMessages, err := cs.chatMessages(*userID, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.SearchMessagesResponse{
+		Results: make([]*pb.SearchResult, 0, len(rows)),
+		HasMore: hasMore,
+	}
+	for i, row := range rows {
+		res.Results = append(res.Results, &pb.SearchResult{
+			Message: chatMessages[i],
+			Snippet: highlightSnippet(row.Snippet),
+		})
+	}
+
+	if hasMore {
+		res.NextCursor = searchCursor(rows[len(rows)-1].ID)
+	}
+
+	return res, nil
+}
+
+// searchCursor adalah id pesan terakhir di halaman; halaman berikutnya
+// berisi pesan dengan id lebih kecil
+func searchCursor(messageID int) string {
+	return strconv.Itoa(messageID)
+}
+
+func parseSearchCursor(cursor string) (int, error) {
+	id, err := strconv.Atoi(cursor)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("cursor must be a message id")
+	}
+	return id, nil
+}
+
+// highlightSnippet meng-escape snippet lalu mengganti penanda ts_headline
+// dengan tag <mark>, sehingga isi pesan tidak pernah dianggap HTML
+func highlightSnippet(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, highlightStart, "<mark>")
+	return strings.ReplaceAll(snippet, highlightStop, "</mark>")
+}
+
+// parseSearchDate menerima RFC3339 atau YYYY-MM-DD. Untuk batas akhir,
+// tanggal tanpa jam berarti sampai akhir hari tersebut.
+func parseSearchDate(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date must be RFC3339 or YYYY-MM-DD")
+	}
+
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSearchCursor(t *testing.T) {
+	for _, id := range []int{1, 42, 2147483647} {
+		got, err := parseSearchCursor(searchCursor(id))
+		if err != nil || got != id {
+			t.Errorf("parseSearchCursor(searchCursor(%d)) = %d, %v", id, got, err)
+		}
+	}
+
+	for _, cursor := range []string{"abc", "0", "-5", "12.5", "2024-05-01T10:00:00Z"} {
+		if _, err := parseSearchCursor(cursor); err == nil {
+			t.Errorf("parseSearchCursor(%q) accepted an invalid cursor", cursor)
+		}
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		snippet string
+		want    string
+	}{
+		{snippet: "tanpa highlight", want: "tanpa highlight"},
+		{snippet: "rapat " + highlightStart + "besok" + highlightStop + " pagi", want: "rapat <mark>besok</mark> pagi"},
+		{snippet: "<b>" + highlightStart + "x" + highlightStop + "</b> & y", want: "&lt;b&gt;<mark>x</mark>&lt;/b&gt; &amp; y"},
+		{snippet: highlightStart + "<script>" + highlightStop, want: "<mark>&lt;script&gt;</mark>"},
+	}
+
+	for _, tt := range tests {
+		if got := highlightSnippet(tt.snippet); got != tt.want {
+			t.Errorf("highlightSnippet(%q) = %q, want %q", tt.snippet, got, tt.want)
+		}
+	}
+}
+
+func TestParseSearchDate(t *testing.T) {
+	tests := []struct {
+		value   string
+		end     bool
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2024-05-01", want: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-05-01", end: true, want: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-05-01T10:30:00Z", end: true, want: time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)},
+		{value: "01/05/2024", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSearchDate(tt.value, tt.end)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSearchDate(%q, %v) error = %v, want error %v", tt.value, tt.end, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !got.Equal(tt.want) {
+			t.Errorf("parseSearchDate(%q, %v) = %v, want %v", tt.value, tt.end, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"chat-service/app/bus"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// deliveryStats menghitung event live yang dikirim ke stream:
+// delivered masuk buffer, deferred adalah event tersimpan yang dilewati dan
+// akan diputar ulang dari Postgres, dropped adalah event sementara (typing,
+// presence) yang dibuang, lagged/resynced adalah jumlah subscriber yang
+// tertinggal dan yang sudah mengejar kembali, urgent adalah event
+// berprioritas tinggi yang dikirim lewat jalur terpisah
+var deliveryStats = expvar.NewMap("chat_delivery")
+
+const (
+	subscriberBuffer    = 64
+	urgentBuffer        = 16
+	busSubscribeTimeout = 5 * time.Second
+)
+
+// subscriber adalah satu stream yang sedang terbuka milik user,
+// satu untuk setiap device/sesi
+type subscriber struct {
+	userID    int
+	deviceID  string // kosong jika client tidak mengirim device_id
+	sessionID string
+	ch        chan *pb.StreamMessagesResponse
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// lagging bernilai true sejak buffer ch pernah penuh sampai stream
+	// selesai mengejar lewat catchUp; lag memberi sinyal ke loop stream
+	lagging atomic.Bool
+	lag     chan struct{}
+
+	// urgent menampung event berprioritas tinggi (mention) agar tetap
+	// sampai walaupun ch penuh atau subscriber sedang tertinggal
+	urgent chan *pb.StreamMessagesResponse
+
+	// lastPushed adalah id pesan tertinggi yang sudah dikirim ke stream, live
+	// maupun replay; replay berikutnya dimulai setelahnya. replayed berisi
+	// pesan dari replay terakhir yang event live-nya belum datang, dikosongkan
+	// setiap replay dimulai. Hanya diakses oleh goroutine stream.
+	lastPushed int
+	replayed   map[int32]bool
+}
+
+// alreadyReplayed melaporkan apakah event live adalah pesan yang sudah
+// dikirim lewat replay. Event live tiap pesan hanya datang sekali, sehingga
+// catatannya langsung dihapus.
+func (sub *subscriber) alreadyReplayed(msg *pb.StreamMessagesResponse) bool {
+	if msg.Type != EventMessage || !sub.replayed[msg.MessageId] {
+		return false
+	}
+	delete(sub.replayed, msg.MessageId)
+	return true
+}
+
+// markPushed dipanggil setelah event berhasil dikirim ke stream
+func (sub *subscriber) markPushed(msg *pb.StreamMessagesResponse) {
+	if msg.Type == EventMessage {
+		sub.lastPushed = max(sub.lastPushed, int(msg.MessageId))
+	}
+}
+
+// send tidak pernah menunggu stream penerima. Jika buffer penuh, subscriber
+// ditandai tertinggal dan semua event berikutnya dilewati sampai stream
+// mengejar dari Postgres, sehingga urutan event tetap terjaga.
+// Event berprioritas tinggi lebih dulu dicoba lewat jalur urgent.
+func (sub *subscriber) send(msg *pb.StreamMessagesResponse) {
+	select {
+	case <-sub.done:
+		return
+	default:
+	}
+
+	if msg.Priority == PriorityHigh {
+		select {
+		case sub.urgent <- msg:
+			deliveryStats.Add("urgent", 1)
+			return
+		default:
+		}
+	}
+
+	if !sub.lagging.Load() {
+		select {
+		case sub.ch <- msg:
+			deliveryStats.Add("delivered", 1)
+			return
+		default:
+		}
+	}
+
+	if durableEvents[msg.Type] {
+		deliveryStats.Add("deferred", 1)
+	} else {
+		deliveryStats.Add("dropped", 1)
+	}
+
+	if !sub.lagging.Swap(true) {
+		deliveryStats.Add("lagged", 1)
+		log.Printf("Stream of user %d session %s is lagging, deferring to offline replay", sub.userID, sub.sessionID)
+		select {
+		case sub.lag <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (sub *subscriber) close() {
+	sub.closeOnce.Do(func() { close(sub.done) })
+}
+
+// busSubscription meneruskan event dari bus ke semua device user di replica ini
+type busSubscription struct {
+	sub  bus.Subscription
+	stop chan struct{}
+}
+
+// subscribe mendaftarkan stream device milik user dan memberi tahu lawan
+// bicara jika ini device pertama yang online. Device dengan session yang
+// sama akan menggantikan stream lamanya. Subscribe ke bus dilakukan di luar
+// ClientsMutex dengan batas waktu; jika gagal stream tidak didaftarkan dan
+// error dikembalikan agar client bisa reconnect.
+func (cs *ChatServiceServer) subscribe(ctx context.Context, userID int, deviceID string) (*subscriber, error) {
+	sessionID := deviceID
+	if sessionID == "" {
+		sessionID = helper.NewSessionID()
+	}
+
+	sub := &subscriber{
+		userID:    userID,
+		deviceID:  deviceID,
+		sessionID: sessionID,
+		ch:        make(chan *pb.StreamMessagesResponse, subscriberBuffer),
+		done:      make(chan struct{}),
+		lag:       make(chan struct{}, 1),
+		urgent:    make(chan *pb.StreamMessagesResponse, urgentBuffer),
+		replayed:  make(map[int32]bool),
+	}
+
+	var pending bus.Subscription
+	for {
+		cs.ClientsMutex.Lock()
+		_, subscribed := cs.busSubs[userID]
+		if subscribed || pending != nil {
+			break
+		}
+		cs.ClientsMutex.Unlock()
+
+		busCtx, cancel := context.WithTimeout(ctx, busSubscribeTimeout)
+		busSub, err := cs.bus.Subscribe(busCtx, userID)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to subscribe to event bus: %v", err)
+		}
+		pending = busSub
+	}
+
+	// Mutex masih dipegang dari loop di atas. Subscription bus yang dibuat
+	// bersamaan oleh device lain milik user yang sama ditutup.
+	if _, ok := cs.busSubs[userID]; !ok {
+		bs := &busSubscription{sub: pending, stop: make(chan struct{})}
+		cs.busSubs[userID] = bs
+		go cs.fanOut(userID, bs)
+		pending = nil
+	}
+
+	sessions, ok := cs.Clients[userID]
+	if !ok {
+		sessions = make(map[string]*subscriber)
+		cs.Clients[userID] = sessions
+	}
+	if old, ok := sessions[sessionID]; ok {
+		old.close()
+	}
+	sessions[sessionID] = sub
+	firstDevice := len(sessions) == 1
+	cs.ClientsMutex.Unlock()
+
+	if pending != nil {
+		pending.Close()
+	}
+
+	if firstDevice {
+		go cs.broadcastPresence(userID, true)
+	}
+
+	return sub, nil
+}
+
+// unsubscribe hanya menghapus device yang terputus; user dianggap offline
+// setelah device terakhirnya terputus
+func (cs *ChatServiceServer) unsubscribe(sub *subscriber) {
+	sub.close()
+
+	cs.ClientsMutex.Lock()
+	sessions := cs.Clients[sub.userID]
+	if sessions[sub.sessionID] == sub {
+		delete(sessions, sub.sessionID)
+	}
+	lastDevice := len(sessions) == 0
+	if lastDevice {
+		delete(cs.Clients, sub.userID)
+
+		if bs, ok := cs.busSubs[sub.userID]; ok {
+			delete(cs.busSubs, sub.userID)
+			close(bs.stop)
+			bs.sub.Close()
+		}
+	}
+	cs.ClientsMutex.Unlock()
+
+	if lastDevice {
+		go cs.typing.stopUser(sub.userID)
+		go cs.broadcastPresence(sub.userID, false)
+	}
+}
+
+// subscribers mengambil salinan daftar stream milik user
+func (cs *ChatServiceServer) subscribers(userID int) []*subscriber {
+	cs.ClientsMutex.Lock()
+	defer cs.ClientsMutex.Unlock()
+
+	subs := make([]*subscriber, 0, len(cs.Clients[userID]))
+	for _, sub := range cs.Clients[userID] {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+// fanOut mengirim event dari bus ke setiap device user yang terhubung ke replica ini
+func (cs *ChatServiceServer) fanOut(userID int, bs *busSubscription) {
+	for {
+		select {
+		case event, ok := <-bs.sub.Events():
+			if !ok {
+				return
+			}
+			for _, sub := range cs.subscribers(userID) {
+				sub.send(event)
+			}
+		case <-bs.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"chat-service/app/bus"
+	"chat-service/config"
+	pb "chat-service/proto/script"
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// newTestServer membuat replica chat-service di atas bus bersama. Database
+// memakai DryRun sehingga query (misalnya presence) tidak pernah dijalankan.
+func newTestServer(t *testing.T, eventBus bus.Bus) *ChatServiceServer {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatalf("open dry-run database: %v", err)
+	}
+
+	return NewChatServer(db, nil, eventBus, nil, config.Config{})
+}
+
+func TestDeliverFanOut(t *testing.T) {
+	type device struct {
+		replica  int
+		deviceID string
+	}
+
+	tests := []struct {
+		name     string
+		replicas int
+		devices  []device // semua milik user 1
+		from     int      // replica yang mengirim event
+	}{
+		{name: "same replica", replicas: 1, devices: []device{{0, "phone"}}},
+		{name: "cross replica", replicas: 2, devices: []device{{1, "phone"}}},
+		{name: "multi device on one replica", replicas: 1, devices: []device{{0, "phone"}, {0, "laptop"}}},
+		{name: "multi device across replicas", replicas: 3, devices: []device{{0, "phone"}, {1, "laptop"}, {2, "tablet"}}, from: 1},
+		{name: "device without id", replicas: 2, devices: []device{{0, ""}, {1, ""}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventBus := bus.NewMemoryBus()
+			replicas := make([]*ChatServiceServer, tt.replicas)
+			for i := range replicas {
+				replicas[i] = newTestServer(t, eventBus)
+			}
+
+			ctx := context.Background()
+			subs := make([]*subscriber, len(tt.devices))
+			for i, d := range tt.devices {
+				sub, err := replicas[d.replica].subscribe(ctx, 1, d.deviceID)
+				if err != nil {
+					t.Fatalf("subscribe: %v", err)
+				}
+				defer replicas[d.replica].unsubscribe(sub)
+				subs[i] = sub
+			}
+
+			// typing bukan event tersimpan sehingga deliver tidak menyentuh database
+			replicas[tt.from].deliver(ctx, 1, &pb.StreamMessagesResponse{Type: EventTyping, ConversationId: 3})
+
+			for i, sub := range subs {
+				select {
+				case event := <-sub.ch:
+					if event.ConversationId != 3 {
+						t.Errorf("device %d received conversation %d, want 3", i, event.ConversationId)
+					}
+				case <-time.After(time.Second):
+					t.Errorf("device %d did not receive the event", i)
+				}
+			}
+		})
+	}
+}
+
+func TestUnsubscribeCleansUp(t *testing.T) {
+	eventBus := bus.NewMemoryBus()
+	cs := newTestServer(t, eventBus)
+	ctx := context.Background()
+
+	phone, err := cs.subscribe(ctx, 1, "phone")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	laptop, err := cs.subscribe(ctx, 1, "laptop")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	cs.unsubscribe(phone)
+	if got := len(cs.subscribers(1)); got != 1 {
+		t.Fatalf("user has %d streams after one device left, want 1", got)
+	}
+	if n, _ := eventBus.Publish(ctx, 1, &pb.StreamMessagesResponse{Type: EventTyping}); n != 1 {
+		t.Fatalf("bus reached %d subscriptions while a device is online, want 1", n)
+	}
+
+	cs.unsubscribe(laptop)
+	cs.ClientsMutex.Lock()
+	_, hasClients := cs.Clients[1]
+	_, hasBus := cs.busSubs[1]
+	cs.ClientsMutex.Unlock()
+	if hasClients || hasBus {
+		t.Fatalf("user is still registered after the last device left (clients %v, bus %v)", hasClients, hasBus)
+	}
+	if n, _ := eventBus.Publish(ctx, 1, &pb.StreamMessagesResponse{Type: EventTyping}); n != 0 {
+		t.Fatalf("bus reached %d subscriptions after the last device left, want 0", n)
+	}
+}
+
+func TestSubscribeReplacesSameDevice(t *testing.T) {
+	cs := newTestServer(t, bus.NewMemoryBus())
+	ctx := context.Background()
+
+	old, err := cs.subscribe(ctx, 1, "phone")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	current, err := cs.subscribe(ctx, 1, "phone")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer cs.unsubscribe(current)
+
+	select {
+	case <-old.done:
+	default:
+		t.Fatal("old stream of the same device was not closed")
+	}
+
+	// Stream lama yang baru selesai tidak boleh menghapus penggantinya
+	cs.unsubscribe(old)
+	if subs := cs.subscribers(1); len(subs) != 1 || subs[0] != current {
+		t.Fatalf("replacement stream was removed by the old stream")
+	}
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultSyncLimit = 100
+	maxSyncLimit     = 500
+)
+
+// durableEvents adalah jenis event yang dicatat di log user dan bisa diambil lewat Sync
+var durableEvents = map[string]bool{
+	EventMessage:         true,
+	EventMessageEdited:   true,
+	EventMessageDeleted:  true,
+	EventReceipt:         true,
+	EventReaction:        true,
+	EventPin:             true,
+	EventAttachmentReady: true,
+	EventMessageExpired:  true,
+	EventMention:         true,
+}
+
+func (cs *ChatServiceServer) Sync(ctx context.Context, req *pb.SyncRequest) (*pb.SyncResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultSyncLimit
+	} else if limit > maxSyncLimit {
+		limit = maxSyncLimit
+	}
+
+	// Event setelah since_seq yang sudah dipangkas tidak bisa dikirim ulang
+	var sequence models.UserSequence
+	err = cs.db.Where("user_id = ?", userID).Limit(1).Find(&sequence).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %v", err)
+	}
+
+	var events []models.UserEvent
+	err = cs.db.Where("user_id = ? AND seq > ?", userID, req.SinceSeq).
+		Order("seq ASC").
+		Limit(limit + 1).
+		Find(&events).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %v", err)
+	}
+
+	hasMore := len(events) > limit
+	if hasMore {
+		events = events[:limit]
+	}
+
+	res := &pb.SyncResponse{
+		Events:    make([]*pb.StreamMessagesResponse, 0, len(events)),
+		LastSeq:   req.SinceSeq,
+		HasMore:   hasMore,
+		Truncated: req.SinceSeq < sequence.PrunedSeq,
+	}
+
+	for _, e := range events {
+		var event pb.StreamMessagesResponse
+		if err := proto.Unmarshal(e.Payload, &event); err != nil {
+			return nil, fmt.Errorf("failed to decode event %d: %v", e.Seq, err)
+		}
+		event.Seq = e.Seq
+		res.Events = append(res.Events, &event)
+		res.LastSeq = e.Seq
+	}
+
+	return res, nil
+}
+
+// recordEvents memberi seq berikutnya milik setiap user pada event dan
+// menyimpannya dengan satu statement. Baris user_sequences dikunci sampai
+// statement selesai sehingga urutan seq sama dengan urutan event tersimpan.
+// Payload disimpan tanpa seq; Sync mengisinya dari kolom seq.
+func (cs *ChatServiceServer) recordEvents(userIDs []int, event *pb.StreamMessagesResponse) (map[int]int64, error) {
+	payload, err := proto.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %v", err)
+	}
+
+	var rows []struct {
+		UserID int
+		Seq    int64
+	}
+	err = cs.db.Raw(`WITH seqs AS (
+			INSERT INTO user_sequences (user_id, last_seq)
+			SELECT id, 1 FROM unnest(?::bigint[]) AS id ORDER BY id
+			ON CONFLICT (user_id) DO UPDATE SET last_seq = user_sequences.last_seq + 1
+			RETURNING user_id, last_seq
+		)
+		INSERT INTO user_events (user_id, seq, type, message_id, payload, created_at)
+		SELECT user_id, last_seq, ?, ?, ?, NOW() FROM seqs
+		RETURNING user_id, seq`,
+		idArray(userIDs), event.Type, event.MessageId, payload).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to record events: %v", err)
+	}
+
+	seqs := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		seqs[row.UserID] = row.Seq
+	}
+
+	return seqs, nil
+}
+
+// idArray menulis id sebagai literal array Postgres, misalnya {1,2,3}
+func idArray(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// pruneUserEvents menghapus satu batch event yang lebih tua dari masa simpan
+// dan mencatat seq terbesar yang terhapus per user di pruned_seq, sehingga
+// Sync tahu client yang tertinggal lebih jauh harus memuat ulang dari awal.
+// Mengembalikan jumlah event terhapus.
+func (cs *ChatServiceServer) pruneUserEvents() (int, error) {
+	days := cs.cfg.Chat.EventRetentionDays
+	if days <= 0 {
+		return 0, nil
+	}
+
+	var deleted int
+	err := cs.db.Raw(`WITH deleted AS (
+			DELETE FROM user_events WHERE id IN (
+				SELECT id FROM user_events WHERE created_at < ? ORDER BY created_at LIMIT ?
+			)
+			RETURNING user_id, seq
+		), pruned AS (
+			UPDATE user_sequences AS s SET pruned_seq = GREATEST(s.pruned_seq, d.seq)
+			FROM (SELECT user_id, MAX(seq) AS seq FROM deleted GROUP BY user_id) AS d
+			WHERE s.user_id = d.user_id
+			RETURNING s.user_id
+		)
+		SELECT COUNT(*) FROM deleted`,
+		time.Now().AddDate(0, 0, -days), sweepBatchSize).
+		Scan(&deleted).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune user events: %v", err)
+	}
+
+	return deleted, nil
+}
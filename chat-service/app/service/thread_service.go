@@ -0,0 +1,199 @@
+package service
+
+import (
+	"chat-service/app/models"
+	"chat-service/helper"
+	pb "chat-service/proto/script"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+func (cs *ChatServiceServer) ListThread(ctx context.Context, req *pb.ListThreadRequest) (*pb.ListThreadResponse, error) {
+
+	userID, err := helper.ParsingJWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing id %s", err)
+	}
+
+	// message_id boleh berupa balasan, thread selalu dibuka dari pesan induknya
+	var root models.Message
+	err = cs.db.Unscoped().First(&root, req.MessageId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("message not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get message: %v", err)
+	}
+
+	if root.ThreadRootID != 0 {
+		if err := cs.db.Unscoped().First(&root, root.ThreadRootID).Error; err != nil {
+			return nil, fmt.Errorf("failed to get thread root: %v", err)
+		}
+	}
+
+	if err := cs.checkConversationMember(root.ConversationID, *userID); err != nil {
+		return nil, err
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	} else if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	query := cs.db.Scopes(notExpired).Where("thread_root_id = ?", root.ID)
+	if req.Cursor != "" {
+		cursorID, err := strconv.Atoi(req.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("cursor must be a message id")
+		}
+		query = query.Where("id > ?", cursorID)
+	}
+
+	var replies []models.Message
+	if err := query.Order("id ASC").Limit(limit + 1).Find(&replies).Error; err != nil {
+		return nil, fmt.Errorf("failed to get thread replies: %v", err)
+	}
+
+	hasMore := len(replies) > limit
+	if hasMore {
+		replies = replies[:limit]
+	}
+
+	messages, err := cs.chatMessages(*userID, append([]models.Message{root}, replies...))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.ListThreadResponse{
+		Root:    messages[0],
+		Replies: messages[1:],
+		HasMore: hasMore,
+	}
+	if root.DeletedAt != nil && root.DeletedAt.Valid {
+		res.Root.Content = ""
+	}
+	if hasMore {
+		res.NextCursor = strconv.Itoa(replies[len(replies)-1].ID)
+	}
+
+	return res, nil
+}
+
+// applyReply mengisi reply_to_message_id dan thread_root_id pesan baru.
+// Pesan yang dibalas harus berada di percakapan yang sama.
+func applyReply(tx *gorm.DB, message *models.Message, req *pb.SendMessageRequest) error {
+	if req.ReplyToMessageId == 0 {
+		if req.InThread {
+			return fmt.Errorf("reply_to_message_id is required for thread replies")
+		}
+		return nil
+	}
+
+	var parent models.Message
+	err := tx.Scopes(notExpired).First(&parent, req.ReplyToMessageId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("replied message not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to get replied message: %v", err)
+	}
+
+	if parent.ConversationID != message.ConversationID {
+		return fmt.Errorf("replied message is not part of this conversation")
+	}
+
+	message.ReplyToMessageID = parent.ID
+
+	// Balasan untuk pesan di dalam thread otomatis ikut thread yang sama
+	switch {
+	case parent.ThreadRootID != 0:
+		message.ThreadRootID = parent.ThreadRootID
+	case req.InThread:
+		message.ThreadRootID = parent.ID
+	}
+
+	return nil
+}
+
+// chatMessages mengubah pesan ke format proto beserta jumlah balasan
+// thread, cuplikan pesan yang dibalas, lampiran dan reaksi dari sudut
+// pandang viewerID
+func (cs *ChatServiceServer) chatMessages(viewerID int, messages []models.Message) ([]*pb.ChatMessage, error) {
+	result := make([]*pb.ChatMessage, 0, len(messages))
+	if len(messages) == 0 {
+		return result, nil
+	}
+
+	var ids, replyIDs []int
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+		if message.ReplyToMessageID != 0 {
+			replyIDs = append(replyIDs, message.ReplyToMessageID)
+		}
+	}
+
+	var counts []struct {
+		ThreadRootID int
+		Count        int
+	}
+	err := cs.db.Model(&models.Message{}).
+		Select("thread_root_id, COUNT(*) AS count").
+		Where("thread_root_id IN ?", ids).
+		Group("thread_root_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count thread replies: %v", err)
+	}
+
+	replyCounts := make(map[int]int)
+	for _, c := range counts {
+		replyCounts[c.ThreadRootID] = c.Count
+	}
+
+	quoted := make(map[int]*pb.QuotedMessage)
+	if len(replyIDs) > 0 {
+		var parents []models.Message
+		if err := cs.db.Unscoped().Where("id IN ?", replyIDs).Find(&parents).Error; err != nil {
+			return nil, fmt.Errorf("failed to get replied messages: %v", err)
+		}
+
+		for _, parent := range parents {
+			q := &pb.QuotedMessage{Id: int32(parent.ID), SenderId: int32(parent.SenderID), Content: parent.Content}
+			if parent.DeletedAt != nil && parent.DeletedAt.Valid {
+				q.Content = ""
+				q.Deleted = true
+			}
+			quoted[parent.ID] = q
+		}
+	}
+
+	reactions, err := cs.reactionCounts(viewerID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachments []models.Attachment
+	if err := cs.db.Where("message_id IN ?", ids).Order("id ASC").Find(&attachments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %v", err)
+	}
+
+	attachmentsByMessage := make(map[int][]models.Attachment)
+	for _, attachment := range attachments {
+		attachmentsByMessage[attachment.MessageID] = append(attachmentsByMessage[attachment.MessageID], attachment)
+	}
+
+	for _, message := range messages {
+		message.Attachments = attachmentsByMessage[message.ID]
+		m := chatMessage(message)
+		m.ReplyCount = int32(replyCounts[message.ID])
+		m.ReplyTo = quoted[message.ReplyToMessageID]
+		m.Reactions = reactions[message.ID]
+		result = append(result, m)
+	}
+
+	return result, nil
+}
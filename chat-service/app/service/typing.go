@@ -0,0 +1,108 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// typingThrottle adalah jarak minimal antar event typing true yang
+	// diteruskan untuk user dan percakapan yang sama
+	typingThrottle = 3 * time.Second
+	// typingTimeout: tanpa pembaruan selama ini, typing dianggap berhenti
+	typingTimeout = 6 * time.Second
+)
+
+type typingKey struct {
+	userID         int
+	conversationID int
+}
+
+type typingState struct {
+	lastSent time.Time
+	timer    *time.Timer
+	stop     func() // mengirim typing false ke peserta lain
+}
+
+// typingTracker menyimpan status mengetik di memori replica saja,
+// typing tidak pernah ditulis ke Postgres maupun log event
+type typingTracker struct {
+	mu     sync.Mutex
+	states map[typingKey]*typingState
+}
+
+func newTypingTracker() *typingTracker {
+	return &typingTracker{states: make(map[typingKey]*typingState)}
+}
+
+// start mencatat bahwa user sedang mengetik dan memperpanjang waktu
+// kedaluwarsanya. Mengembalikan false jika event perlu di-throttle.
+func (t *typingTracker) start(key typingKey, stop func()) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if state, ok := t.states[key]; ok {
+		state.timer.Reset(typingTimeout)
+		if time.Since(state.lastSent) < typingThrottle {
+			return false
+		}
+		state.lastSent = time.Now()
+		return true
+	}
+
+	state := &typingState{lastSent: time.Now(), stop: stop}
+	state.timer = time.AfterFunc(typingTimeout, func() {
+		if t.remove(key, state) {
+			stop()
+		}
+	})
+	t.states[key] = state
+	return true
+}
+
+// stop menghapus status mengetik, mengembalikan false jika user memang
+// tidak sedang mengetik sehingga tidak perlu mengirim apa-apa
+func (t *typingTracker) stop(key typingKey) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.states[key]
+	if !ok {
+		return false
+	}
+
+	state.timer.Stop()
+	delete(t.states, key)
+	return true
+}
+
+// stopUser menghentikan semua status mengetik milik user,
+// dipakai saat device terakhirnya terputus
+func (t *typingTracker) stopUser(userID int) {
+	var stops []func()
+
+	t.mu.Lock()
+	for key, state := range t.states {
+		if key.userID == userID {
+			state.timer.Stop()
+			delete(t.states, key)
+			stops = append(stops, state.stop)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, stop := range stops {
+		stop()
+	}
+}
+
+func (t *typingTracker) remove(key typingKey, state *typingState) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.states[key] != state {
+		return false
+	}
+	delete(t.states, key)
+	return true
+}
@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore menyimpan isi file lampiran. Metadata lampiran tetap
+// disimpan di Postgres, BlobStore hanya mengenal key dan isi file.
+type BlobStore interface {
+	// Put menyimpan isi r dengan panjang size byte di bawah key
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+
+	// Get membuka isi blob, mengembalikan ErrNotFound jika key tidak ada
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	Delete(ctx context.Context, key string) error
+}
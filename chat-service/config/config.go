@@ -1,6 +1,8 @@
 package config
 
 import (
+	"strings"
+
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
@@ -12,8 +14,14 @@ type Config struct {
 	Migration bool
 	Seeder    bool
 	PublicKey string
+	EventBus  string
 	Database  Database
 	Redis     Redis
+	Chat      Chat
+	Storage   Storage
+
+	// MetricsAddr adalah alamat HTTP untuk /debug/vars (expvar), kosong berarti nonaktif
+	MetricsAddr string
 }
 
 type Database struct {
@@ -36,6 +44,25 @@ type Redis struct {
 	Prefix   string
 }
 
+type Chat struct {
+	EditWindowMinutes int
+	// EventRetentionDays adalah masa simpan log event untuk Sync, 0 berarti selamanya
+	EventRetentionDays int
+}
+
+type Storage struct {
+	Driver        string
+	LocalPath     string
+	S3Endpoint    string
+	S3Region      string
+	S3Bucket      string
+	S3AccessKey   string
+	S3SecretKey   string
+	MaxSizeMB     int
+	AllowedTypes  []string
+	ThumbnailSize int
+}
+
 func SetConfig() (Config, error) {
 
 	log, _ := zap.NewProduction()
@@ -51,6 +78,15 @@ func SetConfig() (Config, error) {
 	viper.SetDefault("DBUser", "postgres")
 	viper.SetDefault("DBPassword", "admin")
 	viper.SetDefault("DBName", "database")
+	viper.SetDefault("EVENT_BUS", "redis")
+	viper.SetDefault("METRICS_ADDR", "127.0.0.1:50064")
+	viper.SetDefault("EDIT_WINDOW_MINUTES", 15)
+	viper.SetDefault("EVENT_RETENTION_DAYS", 30)
+	viper.SetDefault("STORAGE_DRIVER", "local")
+	viper.SetDefault("STORAGE_LOCAL_PATH", "./uploads")
+	viper.SetDefault("ATTACHMENT_MAX_SIZE_MB", 25)
+	viper.SetDefault("THUMBNAIL_SIZE", 320)
+	viper.SetDefault("ATTACHMENT_ALLOWED_TYPES", "image/jpeg,image/png,image/gif,video/mp4,audio/mpeg,application/pdf,application/zip,text/plain")
 
 	viper.AutomaticEnv()
 
@@ -66,6 +102,7 @@ func SetConfig() (Config, error) {
 		Migration: viper.GetBool("AUTO_MIGRATE"),
 		Seeder:    viper.GetBool("SEEDER"),
 		PublicKey: viper.GetString("PUBLIC_KEY"),
+		EventBus:  viper.GetString("EVENT_BUS"),
 
 		Database: Database{
 			DBName:         viper.GetString("DB_NAME"),
@@ -86,6 +123,26 @@ func SetConfig() (Config, error) {
 			Password: viper.GetString("REDIS_PASSWORD"),
 			Prefix:   viper.GetString("REDIS_PREFIX"),
 		},
+
+		Chat: Chat{
+			EditWindowMinutes:  viper.GetInt("EDIT_WINDOW_MINUTES"),
+			EventRetentionDays: viper.GetInt("EVENT_RETENTION_DAYS"),
+		},
+
+		Storage: Storage{
+			Driver:        viper.GetString("STORAGE_DRIVER"),
+			LocalPath:     viper.GetString("STORAGE_LOCAL_PATH"),
+			S3Endpoint:    viper.GetString("S3_ENDPOINT"),
+			S3Region:      viper.GetString("S3_REGION"),
+			S3Bucket:      viper.GetString("S3_BUCKET"),
+			S3AccessKey:   viper.GetString("S3_ACCESS_KEY"),
+			S3SecretKey:   viper.GetString("S3_SECRET_KEY"),
+			MaxSizeMB:     viper.GetInt("ATTACHMENT_MAX_SIZE_MB"),
+			AllowedTypes:  strings.Split(viper.GetString("ATTACHMENT_ALLOWED_TYPES"), ","),
+			ThumbnailSize: viper.GetInt("THUMBNAIL_SIZE"),
+		},
+
+		MetricsAddr: viper.GetString("METRICS_ADDR"),
 	}
 
 	return config, nil
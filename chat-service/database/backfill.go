@@ -0,0 +1,106 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// BackfillConversations mengisi conversation_id pesan yang dibuat sebelum ada
+// tabel conversations. Percakapan 1:1 dibuat per pasangan pengirim/penerima
+// dan percakapan group per group, lengkap dengan pesertanya. Pesan lama
+// dianggap sudah dibaca agar tidak muncul sebagai unread atau diputar ulang
+// ke device baru. Aman dijalankan berulang kali; tanpa pesan lama tidak ada
+// yang diubah.
+func BackfillConversations(db *gorm.DB) error {
+	var pending bool
+	err := db.Raw(`SELECT EXISTS (
+		SELECT 1 FROM messages WHERE conversation_id IS NULL OR conversation_id = 0
+	)`).Scan(&pending).Error
+	if err != nil {
+		return fmt.Errorf("failed to check messages without conversation: %v", err)
+	}
+	if !pending {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT INTO conversations (type, direct_key, last_message_id, last_activity_at, created_at)
+			SELECT 'direct', LEAST(sender_id, reciever_id) || ':' || GREATEST(sender_id, reciever_id),
+				0, MAX(created_at), MIN(created_at)
+			FROM messages
+			WHERE (conversation_id IS NULL OR conversation_id = 0)
+				AND COALESCE(group_id, 0) = 0 AND reciever_id <> 0
+			GROUP BY LEAST(sender_id, reciever_id), GREATEST(sender_id, reciever_id)
+			ON CONFLICT (direct_key) DO NOTHING`).Error
+		if err != nil {
+			return fmt.Errorf("failed to backfill direct conversations: %v", err)
+		}
+
+		err = tx.Exec(`INSERT INTO conversations (type, group_id, last_message_id, last_activity_at, created_at)
+			SELECT 'group', m.group_id, 0, MAX(m.created_at), MIN(m.created_at)
+			FROM messages AS m JOIN groups AS g ON g.id = m.group_id
+			WHERE m.conversation_id IS NULL OR m.conversation_id = 0
+			GROUP BY m.group_id
+			ON CONFLICT (group_id) DO NOTHING`).Error
+		if err != nil {
+			return fmt.Errorf("failed to backfill group conversations: %v", err)
+		}
+
+		// Percakapan yang tersentuh dicatat di temp table agar langkah berikutnya
+		// tidak perlu mengirim daftar id yang bisa sangat panjang
+		err = tx.Exec(`CREATE TEMP TABLE backfilled_conversations (id BIGINT PRIMARY KEY) ON COMMIT DROP`).Error
+		if err != nil {
+			return fmt.Errorf("failed to create backfill table: %v", err)
+		}
+
+		err = tx.Exec(`WITH updated AS (
+				UPDATE messages AS m SET conversation_id = c.id
+				FROM conversations AS c
+				WHERE (m.conversation_id IS NULL OR m.conversation_id = 0)
+					AND ((COALESCE(m.group_id, 0) = 0 AND m.reciever_id <> 0
+							AND c.direct_key = LEAST(m.sender_id, m.reciever_id) || ':' || GREATEST(m.sender_id, m.reciever_id))
+						OR (COALESCE(m.group_id, 0) <> 0 AND c.group_id = m.group_id))
+				RETURNING m.conversation_id
+			)
+			INSERT INTO backfilled_conversations (id)
+			SELECT DISTINCT conversation_id FROM updated`).Error
+		if err != nil {
+			return fmt.Errorf("failed to backfill message conversations: %v", err)
+		}
+
+		err = tx.Exec(`INSERT INTO conversation_members (conversation_id, user_id, last_read_message_id, created_at)
+			SELECT m.conversation_id, x.user_id, MAX(m.id), NOW()
+			FROM messages AS m
+			CROSS JOIN LATERAL (VALUES (m.sender_id), (m.reciever_id)) AS x(user_id)
+			WHERE m.conversation_id IN (SELECT id FROM backfilled_conversations) AND COALESCE(m.group_id, 0) = 0
+			GROUP BY m.conversation_id, x.user_id
+			ON CONFLICT DO NOTHING`).Error
+		if err != nil {
+			return fmt.Errorf("failed to backfill direct conversation members: %v", err)
+		}
+
+		err = tx.Exec(`INSERT INTO conversation_members (conversation_id, user_id, last_read_message_id, created_at)
+			SELECT c.id, gm.user_id, (SELECT MAX(id) FROM messages WHERE conversation_id = c.id), NOW()
+			FROM conversations AS c JOIN group_members AS gm ON gm.group_id = c.group_id
+			WHERE c.id IN (SELECT id FROM backfilled_conversations)
+			ON CONFLICT DO NOTHING`).Error
+		if err != nil {
+			return fmt.Errorf("failed to backfill group conversation members: %v", err)
+		}
+
+		err = tx.Exec(`UPDATE conversations AS c SET last_message_id = m.id, last_activity_at = m.created_at
+			FROM (
+				SELECT DISTINCT ON (conversation_id) conversation_id, id, created_at
+				FROM messages
+				WHERE conversation_id IN (SELECT id FROM backfilled_conversations) AND deleted_at IS NULL
+				ORDER BY conversation_id, id DESC
+			) AS m
+			WHERE c.id = m.conversation_id AND m.id > COALESCE(c.last_message_id, 0)`).Error
+		if err != nil {
+			return fmt.Errorf("failed to backfill last messages: %v", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SearchConfig adalah konfigurasi text search Postgres untuk isi pesan.
+// Memakai "simple" karena percakapan bercampur bahasa Indonesia dan Inggris.
+// Query pencarian harus memakai konfigurasi yang sama agar index terpakai.
+const SearchConfig = "simple"
+
+// MigrateIndexes membuat index yang tidak bisa dideklarasikan lewat tag gorm
+func MigrateIndexes(db *gorm.DB) error {
+	err := db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_messages_content_search
+		ON messages USING GIN (to_tsvector('%s', content))`, SearchConfig)).Error
+	if err != nil {
+		return fmt.Errorf("failed to create message search index: %v", err)
+	}
+
+	// client_message_id unik per pengirim. Pesan lama dari kiriman ke beberapa
+	// penerima memakai nilai yang sama, sehingga selain pesan pertama diberi
+	// akhiran "#<id pesan>" sebelum index dibuat
+	err = db.Exec(`UPDATE messages SET client_message_id = client_message_id || '#' || id
+		WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY sender_id, client_message_id ORDER BY id) AS n
+				FROM messages WHERE client_message_id <> ''
+			) AS d
+			WHERE d.n > 1
+		)`).Error
+	if err != nil {
+		return fmt.Errorf("failed to deduplicate client message ids: %v", err)
+	}
+
+	err = db.Exec(`DROP INDEX IF EXISTS idx_messages_client_message_id`).Error
+	if err != nil {
+		return fmt.Errorf("failed to drop client message id index: %v", err)
+	}
+
+	err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_sender_client_message_id
+		ON messages (sender_id, client_message_id)
+		WHERE client_message_id <> ''`).Error
+	if err != nil {
+		return fmt.Errorf("failed to create client message id index: %v", err)
+	}
+
+	// Dipakai sweeper untuk memangkas event yang melewati masa simpan
+	err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_user_events_created_at
+		ON user_events (created_at)`).Error
+	if err != nil {
+		return fmt.Errorf("failed to create user event index: %v", err)
+	}
+
+	return nil
+}
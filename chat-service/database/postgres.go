@@ -40,10 +40,32 @@ func SetDatabase(cfg config.Config) (*gorm.DB, error) {
 
 		err := db.AutoMigrate(
 			&models.Message{},
+			&models.Group{},
+			&models.GroupMember{},
+			&models.Conversation{},
+			&models.ConversationMember{},
+			&models.MessageReceipt{},
+			&models.DeviceCursor{},
+			&models.UserEvent{},
+			&models.UserSequence{},
+			&models.MessageEdit{},
+			&models.MessageReaction{},
+			&models.Attachment{},
+			&models.ScheduledMessage{},
+			&models.PinnedMessage{},
+			&models.Mention{},
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to make migration: " + err.Error())
 		}
+
+		if err := MigrateIndexes(db); err != nil {
+			return nil, err
+		}
+
+		if err := BackfillConversations(db); err != nil {
+			return nil, err
+		}
 	}
 	//
 	// 	if cfg.Seeder {
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"chat-service/config"
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func SetRedis(cfg config.Config) (*redis.Client, error) {
+	addr := cfg.Redis.Url
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: cfg.Redis.Password,
+	})
+
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %v", err)
+	}
+
+	return rdb, nil
+}
@@ -0,0 +1,27 @@
+package helper
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// DeviceID mengambil device_id yang dikirim client lewat metadata,
+// atau string kosong jika client tidak mengirimnya
+func DeviceID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md["device_id"]; len(ids) > 0 {
+			return ids[0]
+		}
+	}
+	return ""
+}
+
+// NewSessionID membuat id acak untuk koneksi yang tidak punya device_id
+func NewSessionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
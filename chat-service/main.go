@@ -1,12 +1,18 @@
 package main
 
 import (
+	"chat-service/app/bus"
 	"chat-service/app/models"
 	"chat-service/app/service"
+	"chat-service/app/storage"
 	"chat-service/config"
+	"chat-service/database"
+	"context"
+	_ "expvar"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 
 	pb "chat-service/proto/script"
 
@@ -32,11 +38,73 @@ func main() {
 
 	log.Println("Database connected")
 
-	chatservice := service.NewChatServer(db)
+	rdb, err := database.SetRedis(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var eventBus bus.Bus
+	switch cfg.EventBus {
+	case "memory":
+		eventBus = bus.NewMemoryBus()
+	default:
+		eventBus = bus.NewRedisBus(rdb, cfg.Redis.Prefix)
+	}
+	log.Printf("Using %s event bus", cfg.EventBus)
+
+	var store storage.BlobStore
+	switch cfg.Storage.Driver {
+	case "s3":
+		store, err = storage.NewS3Store(cfg.Storage.S3Endpoint, cfg.Storage.S3Region, cfg.Storage.S3Bucket,
+			cfg.Storage.S3AccessKey, cfg.Storage.S3SecretKey)
+	default:
+		store, err = storage.NewLocalStore(cfg.Storage.LocalPath)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Using %s attachment storage", cfg.Storage.Driver)
+
+	chatservice := service.NewChatServer(db, rdb, eventBus, store, cfg)
 
-	db.AutoMigrate(&models.Message{})
+	db.AutoMigrate(
+		&models.Message{},
+		&models.Group{},
+		&models.GroupMember{},
+		&models.Conversation{},
+		&models.ConversationMember{},
+		&models.MessageReceipt{},
+		&models.DeviceCursor{},
+		&models.UserEvent{},
+		&models.UserSequence{},
+		&models.MessageEdit{},
+		&models.MessageReaction{},
+		&models.Attachment{},
+		&models.ScheduledMessage{},
+		&models.PinnedMessage{},
+		&models.Mention{},
+	)
+	if err := database.MigrateIndexes(db); err != nil {
+		log.Fatal(err)
+	}
+	if err := database.BackfillConversations(db); err != nil {
+		log.Fatal(err)
+	}
 	log.Println("Database migration complete")
 
+	go chatservice.RunMediaWorker(context.Background())
+	go chatservice.RunScheduler(context.Background())
+	go chatservice.RunRetentionSweeper(context.Background())
+
+	if cfg.MetricsAddr != "" {
+		go func() {
+			log.Printf("Metrics server started on %s", cfg.MetricsAddr)
+			if err := http.ListenAndServe(cfg.MetricsAddr, nil); err != nil {
+				log.Printf("Metrics server stopped: %v", err)
+			}
+		}()
+	}
+
 	grpcServer := grpc.NewServer()
 	pb.RegisterChatServiceServer(grpcServer, chatservice)
 